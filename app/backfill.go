@@ -0,0 +1,109 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/mwinters0/hnjobs/db"
+	"github.com/mwinters0/hnjobs/hn"
+	"time"
+)
+
+type BackfillOptions struct {
+	Context context.Context
+	Status  chan<- FetchStatusUpdate
+	Since   time.Time // fetch stories posted after this
+	TTLSec  int64
+	Source  Source // whoishiring if nil
+}
+
+// BackfillAsync fetches all of the source's stories since bo.Since, newest first.  Stories which were completely
+// fetched before are skipped, so if we're interrupted we can simply run again to resume.  Like FetchAsync, progress
+// is reported on bo.Status, which is closed when we're done.
+func BackfillAsync(bo BackfillOptions) {
+	if bo.Source == nil {
+		bo.Source = NewWhoIsHiringSource(nil)
+	}
+	numJobsFetched := 0
+	notifyCompletion := func(msg string, v int, e error, fatal bool) {
+		// Just a single place to close() on completion
+		if fatal {
+			bo.Status <- FetchStatusUpdate{UpdateTypeFatal, msg, v, e, 0}
+		} else {
+			bo.Status <- FetchStatusUpdate{UpdateTypeDone, msg, v, e, 0}
+		}
+		close(bo.Status)
+	}
+
+	genericStatus(
+		fmt.Sprintf("Searching for %s stories since %s...", bo.Source.Name(), bo.Since.Format("January 2006")),
+		bo.Status,
+	)
+	var stories []*hn.Story
+	numPreviouslyFetched := 0
+	for s, err := range bo.Source.DiscoverStories(bo.Context) {
+		if err != nil {
+			notifyCompletion("Error finding job stories", 0, err, true)
+			return
+		}
+		if s.GoTime.Before(bo.Since) {
+			break // newest first, so we're done
+		}
+		dbStory, err := db.GetStoryById(s.Id)
+		if err != nil && !errors.Is(err, db.ErrNoResults) {
+			notifyCompletion("Failure checking DB for existing story.", 0, err, true)
+			return
+		}
+		if err == nil && dbStory.FetchedTime != 0 {
+			// FetchAsync only sets fetched_time once the whole story is done
+			numPreviouslyFetched++
+			continue
+		}
+		stories = append(stories, s)
+	}
+	genericStatus(
+		fmt.Sprintf("Found %d stories to fetch (%d were already fetched)", len(stories), numPreviouslyFetched),
+		bo.Status,
+	)
+
+	for i, s := range stories {
+		genericStatus(fmt.Sprintf("[%d/%d] Fetching \"%s\"", i+1, len(stories), s.Title), bo.Status)
+		status := make(chan FetchStatusUpdate)
+		fetcher := NewFetcher(FetchOptions{
+			Context: bo.Context,
+			Status:  status,
+			StoryID: s.Id,
+			TTLSec:  bo.TTLSec,
+			Source:  bo.Source,
+		})
+		results := make(chan *FetchResult, 1)
+		go func() {
+			results <- fetcher.Run()
+		}()
+		for fsu := range status {
+			switch fsu.UpdateType {
+			case UpdateTypeFatal, UpdateTypeDone:
+				genericStatus(fsu.Message, bo.Status)
+			default:
+				bo.Status <- fsu
+			}
+		}
+		result := <-results
+		numJobsFetched += result.TotalJobs()
+		if result.Err != nil {
+			notifyCompletion("Backfill failed, run it again to resume", numJobsFetched, result.Err, true)
+			return
+		}
+		if result.Cancelled {
+			notifyCompletion("Backfill cancelled, run it again to resume", numJobsFetched, nil, false)
+			return
+		}
+	}
+
+	notifyCompletion(
+		fmt.Sprintf("Backfill done. Fetched %d jobs from %d stories.", numJobsFetched, len(stories)),
+		numJobsFetched,
+		nil,
+		false,
+	)
+}
@@ -0,0 +1,100 @@
+package app
+
+import (
+	"context"
+	"github.com/mwinters0/hnjobs/db"
+	"github.com/mwinters0/hnjobs/hn"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackfillResume(t *testing.T) {
+	setupAppTest(t, nil)
+
+	story := func(id int, postedTime int64, kid int) map[string]any {
+		return map[string]any{
+			"by":    "whoishiring",
+			"id":    id,
+			"kids":  []int{kid},
+			"time":  postedTime,
+			"title": "Ask HN: Who is hiring?",
+			"type":  "story",
+		}
+	}
+	comment := func(id int, parent int) map[string]any {
+		return map[string]any{
+			"by": "someone", "id": id, "parent": parent, "text": "Acme Corp | SRE | Remote", "time": 1727794900,
+			"type": "comment",
+		}
+	}
+	// 300 is new, 200 was completely fetched, and fetching 100 was interrupted.  There's no comment 201 in the
+	// cassette, so refetching 200 would fail.
+	lines := []string{
+		cassetteLine(t, "/user/whoishiring/submitted.json", "[300, 200, 100]"),
+		cassetteItem(t, story(300, 1727794816, 301)),
+		cassetteItem(t, story(200, 1725116416, 201)),
+		cassetteItem(t, story(100, 1722438016, 101)),
+		cassetteLine(t, "/updates.json", mustJSON(t, map[string]any{"items": []int{}, "profiles": []string{}})),
+		cassetteItem(t, comment(301, 300)),
+		cassetteItem(t, comment(101, 100)),
+	}
+	path := filepath.Join(t.TempDir(), "cassette.jsonl")
+	err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = hn.UseCassette(path, hn.CassetteReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id, fetchedTime := range map[int]int64{200: time.Now().Unix(), 100: 0} {
+		err = db.UpsertStory(&hn.Story{
+			Id: id, Title: "Ask HN: Who is hiring?", FetchedTime: fetchedTime, Source: SourceNameWhoIsHiring,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	status := make(chan FetchStatusUpdate)
+	go BackfillAsync(BackfillOptions{
+		Context: context.Background(),
+		Status:  status,
+		Since:   time.Unix(1700000000, 0),
+		TTLSec:  86400,
+	})
+	var last FetchStatusUpdate
+	var messages []string
+	for fsu := range status {
+		last = fsu
+		messages = append(messages, fsu.Message)
+	}
+	if last.UpdateType != UpdateTypeDone || last.Error != nil {
+		t.Fatalf("Expected the backfill to finish, got %q (%v)", last.Message, last.Error)
+	}
+	if last.Value != 2 {
+		t.Errorf("Expected 2 jobs from stories 300 and 100, got %d", last.Value)
+	}
+	if !strings.Contains(strings.Join(messages, "\n"), "Found 2 stories to fetch (1 were already fetched)") {
+		t.Errorf("Expected story 200 to be skipped, got:\n%s", strings.Join(messages, "\n"))
+	}
+	for id, want := range map[int]int{300: 1, 200: 0, 100: 1} {
+		jobs, err := db.GetAllJobsByStoryId(id, db.OrderNone)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(jobs) != want {
+			t.Errorf("Expected %d jobs in story %d, got %d", want, id, len(jobs))
+		}
+		s, err := db.GetStoryById(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.FetchedTime == 0 {
+			t.Errorf("Expected story %d to be marked as fetched", id)
+		}
+	}
+}
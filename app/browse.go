@@ -1,6 +1,7 @@
 package app
 
 import (
+	"cmp"
 	"context"
 	"database/sql"
 	"errors"
@@ -9,13 +10,16 @@ import (
 	"github.com/mwinters0/hnjobs/config"
 	"github.com/mwinters0/hnjobs/db"
 	"github.com/mwinters0/hnjobs/hn"
+	"github.com/mwinters0/hnjobs/plaintext"
 	"github.com/mwinters0/hnjobs/regionlist"
 	"github.com/mwinters0/hnjobs/sanitview"
 	"github.com/mwinters0/hnjobs/scoring"
+	"github.com/mwinters0/hnjobs/techstack"
+	"github.com/mwinters0/hnjobs/textdiff"
 	"github.com/mwinters0/hnjobs/theme"
 	"github.com/rivo/tview"
-	"html"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -54,9 +58,10 @@ var displayStats = struct {
 	numBelowThreshold        int
 	numUninterested          int
 	numUninterestedDisplayed int // for deciding whether 'x' performs "show uninterested" or "hide"
+	numWithdrawn             int
 	numHidden                int
 }{
-	0, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0,
 }
 
 type DisplayStory struct {
@@ -68,16 +73,26 @@ var displayOptions = struct {
 	threshold          int
 	showBelowThreshold bool
 	showUninterested   bool
+	showWithdrawn      bool
 	curStory           *DisplayStory
 	urlFootnotes       bool
 }{
 	threshold:          1,
 	showBelowThreshold: false,
 	showUninterested:   false,
+	showWithdrawn:      false,
 	curStory:           &DisplayStory{Story: &hn.Story{Id: 0}},
 	urlFootnotes:       false,
 }
 
+// browseSource is the Source whose stories we show and fetch.  S switches between the sources.
+var browseSource = struct {
+	name     string
+	storyIDs []int // for the storylist source
+}{
+	name: SourceNameWhoIsHiring,
+}
+
 // TODO make me responsive
 const maxCompanyNameDisplayLength = 20
 
@@ -89,12 +104,14 @@ func reset() {
 		threshold          int
 		showBelowThreshold bool
 		showUninterested   bool
+		showWithdrawn      bool
 		curStory           *DisplayStory
 		urlFootnotes       bool
 	}{
 		threshold:          1,
 		showBelowThreshold: false,
 		showUninterested:   false,
+		showWithdrawn:      false,
 		curStory:           &DisplayStory{Story: &hn.Story{Id: 0}},
 		urlFootnotes:       false,
 	}
@@ -103,9 +120,10 @@ func reset() {
 		numBelowThreshold        int
 		numUninterested          int
 		numUninterestedDisplayed int
+		numWithdrawn             int
 		numHidden                int
 	}{
-		0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0,
 	}
 	displayJobs = []*DisplayJob{}
 	showingModal = false
@@ -143,12 +161,11 @@ func newDisplayJob(job *db.Job) *DisplayJob {
 	if !job.Interested && !displayOptions.showUninterested {
 		dj.Hidden = true
 	}
+	if job.WithdrawnTime != 0 && !displayOptions.showWithdrawn {
+		dj.Hidden = true
+	}
 
-	str := job.Text
-	str = html.UnescapeString(str)
-	str = strings.ReplaceAll(str, "<p>", "\n\n")
-	str = strings.ReplaceAll(str, "<code>", "") // We always have <pre><code> so don't need both
-	str = strings.ReplaceAll(str, "</code>", "")
+	str := plaintext.FromHTMLKeepFormatting(job.Text) // the same text that scoring sees, so highlights line up
 	str = tview.Escape(str)
 	str = fmt.Sprintf( // as html so it can get formatted later along with other links
 		"%s\n\n\n%s►%s Original HN comment: <a href=\"https://news.ycombinator.com/item?id=%d\"> </a>",
@@ -288,21 +305,33 @@ func newDisplayJob(job *db.Job) *DisplayJob {
 		if r.Colorize != nil && !*r.Colorize {
 			continue
 		}
-		switch r.RuleType {
-		case scoring.TextFound:
-			matchIndices := r.Regex.FindAllStringIndex(str, -1)
-			if matchIndices != nil {
-				if r.Style == nil {
-					if r.Score >= 0 {
-						r.Style = curTheme.JobBody.PositiveHit
-					} else {
-						r.Style = curTheme.JobBody.NegativeHit
+		if !r.Matches(job) {
+			continue // e.g. half of a compound rule, or a rule on a field which didn't match
+		}
+		var matchIndices [][]int
+		for _, h := range r.Highlightable() {
+			switch h.RuleType {
+			case scoring.TextFound:
+				matchIndices = append(matchIndices, h.Regex.FindAllStringIndex(str, -1)...)
+			case scoring.TechIs:
+				for _, m := range techstack.FindAll(str) {
+					if m.Key == h.Tech {
+						matchIndices = append(matchIndices, []int{m.Start, m.End})
 					}
 				}
-				for _, pair := range matchIndices {
-					addStyleRegion(pair[0], pair[1]-1, r.Style)
+			}
+		}
+		if matchIndices != nil {
+			if r.Style == nil {
+				if r.Score >= 0 {
+					r.Style = curTheme.JobBody.PositiveHit
+				} else {
+					r.Style = curTheme.JobBody.NegativeHit
 				}
 			}
+			for _, pair := range matchIndices {
+				addStyleRegion(pair[0], pair[1]-1, r.Style)
+			}
 		}
 	}
 
@@ -365,6 +394,11 @@ func formatDisplayCompany(job *db.Job) string {
 			statusChar = cl.Chars.Applied
 		}
 	}
+	if job.WithdrawnTime != 0 {
+		withdrawnStyle := &sanitview.TViewStyle{Attrs: "ds"}
+		scoreStyle = sanitview.MergeTviewStyles(scoreStyle, withdrawnStyle)
+		nameStyle = sanitview.MergeTviewStyles(nameStyle, withdrawnStyle)
+	}
 	statusCharStyleTag := sanitview.StyleToString(statusCharStyle)
 	scoreStyleTag := sanitview.StyleToString(scoreStyle)
 	nameStyleTag := sanitview.StyleToString(nameStyle)
@@ -382,7 +416,7 @@ func formatDisplayCompany(job *db.Job) string {
 func newDisplayStory(s *hn.Story) *DisplayStory {
 	ds := &DisplayStory{
 		Story:        s,
-		DisplayTitle: s.Title[8:], // chop off "Ask HN: "
+		DisplayTitle: strings.TrimPrefix(s.Title, "Ask HN: "),
 	}
 	return ds
 }
@@ -390,7 +424,7 @@ func newDisplayStory(s *hn.Story) *DisplayStory {
 func setupLatestStory() {
 	if displayOptions.curStory.Id == 0 {
 		// get latest
-		latest, err := db.GetLatestStory()
+		latest, err := db.GetLatestStory(browseSource.name)
 		if errors.Is(err, db.ErrNoResults) {
 			// probably first run
 			return
@@ -402,8 +436,12 @@ func setupLatestStory() {
 	}
 }
 
-func Browse() {
+// Browse runs the TUI, starting with the latest story from the named source.  storyIDs is only needed for the
+// storylist source.
+func Browse(sourceName string, storyIDs []int) {
 	var err error
+	browseSource.name = cmp.Or(sourceName, SourceNameWhoIsHiring)
+	browseSource.storyIDs = storyIDs
 	displayOptions.threshold = config.GetConfig().Display.ScoreThreshold
 	curTheme = theme.GetTheme()
 	setupLatestStory()
@@ -458,6 +496,16 @@ func Browse() {
 				actionListMarkApplied()
 			}
 			return true
+		case 'b':
+			if !showingModal {
+				actionShowScoreBreakdown()
+			}
+			return true
+		case 'd':
+			if !showingModal {
+				actionShowChanges()
+			}
+			return true
 		case 'f':
 			if !showingModal {
 				actionConsiderFetch(false)
@@ -483,16 +531,31 @@ func Browse() {
 				actionListMarkRead()
 			}
 			return true
+		case 'R':
+			if !showingModal {
+				actionBrowseRejects()
+			}
+			return true
 		case 's':
 			if !showingModal {
 				actionRescore()
 			}
 			return true
+		case 'S':
+			if !showingModal {
+				actionSwitchSource()
+			}
+			return true
 		case 'T':
 			if !showingModal {
 				actionToggleShowBelowThreshold()
 			}
 			return true
+		case 'W':
+			if !showingModal {
+				actionToggleShowWithdrawn()
+			}
+			return true
 		case 'x':
 			if !showingModal {
 				actionListMarkInterested()
@@ -512,7 +575,9 @@ func Browse() {
 	tvApp.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		switch event.Rune() {
 		case 'q':
-			tvApp.Stop()
+			if _, typing := tvApp.GetFocus().(*tview.InputField); !typing {
+				tvApp.Stop()
+			}
 		}
 		return event
 	})
@@ -733,13 +798,17 @@ func listNavHandler(index int, mainText string, secondaryText string, shortcut r
 		Fg: curTheme.JobBody.FrameHeader.Bg,
 		Bg: curTheme.JobBody.FrameBackground.Bg,
 	}
+	jobTitle := " Job "
+	if displayJobs[index].WithdrawnTime != 0 {
+		jobTitle = " Job (withdrawn) "
+	}
 	jobFrame.AddText(
 		fmt.Sprintf(
 			"%s%s%s%s%s%s",
 			jFrameHeaderTransitionStyle.AsTag(),
 			"◢",
 			curTheme.JobBody.FrameHeader.AsTag(),
-			" Job ",
+			jobTitle,
 			jFrameHeaderTransitionStyle.AsTag(),
 			"◤",
 		),
@@ -775,6 +844,26 @@ func listNavHandler(index int, mainText string, secondaryText string, shortcut r
 		true, tview.AlignRight, 0,
 	)
 
+	// parsed header as a footer, so you can see the basics before reading the whole thing
+	if summary := displayJobs[index].Header.Summary(); summary != "" {
+		jobFrame.AddText(
+			curTheme.JobBody.CompanyName.AsTag()+" "+tview.Escape(summary)+" ",
+			false, tview.AlignLeft, 0,
+		)
+	}
+	if len(displayJobs[index].Tech) > 0 {
+		var names []string
+		for _, key := range displayJobs[index].Tech {
+			if t := techstack.Get(key); t != nil {
+				names = append(names, t.Name)
+			}
+		}
+		jobFrame.AddText(
+			curTheme.JobBody.CompanyName.AsTag()+" "+tview.Escape(strings.Join(names, ", "))+" ",
+			false, tview.AlignLeft, 0,
+		)
+	}
+
 	fixItemBg(index)
 	if prevSelectedJob != -1 {
 		// restore the previously-selected item back to unmodified
@@ -866,8 +955,13 @@ func actionHelp() {
    - Display
      - ` + hl + `X` + normal + ` - toggle hiding jobs marked uninterested
      - ` + hl + `T` + normal + ` - toggle hiding jobs below score threshold
+     - ` + hl + `W` + normal + ` - toggle hiding jobs withdrawn by the poster
    - Misc
      - ` + hl + `m` + normal + ` - select month (if multiple in DB) / delete old data
+     - ` + hl + `S` + normal + ` - switch source (Who is hiring? / Freelancer / --story-ids)
+     - ` + hl + `R` + normal + ` - review comments which didn't look like jobs
+     - ` + hl + `d` + normal + ` - show what changed since the job was last edited
+     - ` + hl + `b` + normal + ` - show which scoring rules added up to the job's score
      - ` + hl + `s` + normal + ` - reload scoring config and re-score the jobs
 
  For more info: ` + link + url + normal + `
@@ -942,6 +1036,18 @@ func actionToggleShowBelowThreshold() {
 	loadList(curSelectedJobId)
 }
 
+func actionToggleShowWithdrawn() {
+	if !weHaveData() {
+		return
+	}
+	displayOptions.showWithdrawn = !displayOptions.showWithdrawn
+	var curSelectedJobId int
+	if companyList.GetItemCount() > 0 {
+		curSelectedJobId = displayJobs[companyList.GetCurrentItem()].Id
+	}
+	loadList(curSelectedJobId)
+}
+
 func actionToggleShowUninterested() {
 	if !weHaveData() {
 		return
@@ -959,6 +1065,24 @@ func actionToggleShowUninterested() {
 	loadList(curSelectedJobId)
 }
 
+// actionSwitchSource moves on to the next source and its latest story, fetching if we don't have one yet
+func actionSwitchSource() {
+	sources := []string{SourceNameWhoIsHiring, SourceNameFreelancer}
+	if len(browseSource.storyIDs) > 0 {
+		sources = append(sources, SourceNameStoryList)
+	}
+	i := slices.Index(sources, browseSource.name)
+	browseSource.name = sources[(i+1)%len(sources)]
+	reset()
+	setupLatestStory()
+	if displayOptions.curStory.Id == 0 {
+		rebuildHeaderText()
+		actionConsiderFetch(false)
+		return
+	}
+	loadList(0)
+}
+
 func actionConsiderFetch(force bool) {
 	if displayOptions.curStory.Id == 0 {
 		// no story loaded, e.g. first launch with empty database
@@ -1070,7 +1194,7 @@ func fetchJobs(force bool) {
 				loadList(curJobId)
 				if gotNewStory {
 					// suggest switching to it
-					latest, err := db.GetLatestStory()
+					latest, err := db.GetLatestStory(browseSource.name)
 					maybePanic(err)
 					dLatest := newDisplayStory(latest)
 					s := fmt.Sprintf(
@@ -1127,14 +1251,18 @@ func fetchJobs(force bool) {
 	doneCallback := func(err error) {
 		finish()
 	}
+	backend, err := hn.NewBackend(config.GetConfig().Fetch.Backend)
+	maybePanic(err)
+	source, err := NewSource(browseSource.name, browseSource.storyIDs, backend)
+	maybePanic(err)
 	status := make(chan FetchStatusUpdate)
 	fo := FetchOptions{
-		Context:     ctx,
-		Status:      status,
-		ModeForce:   force,
-		StoryID:     0,
-		TTLSec:      config.GetConfig().Cache.TTLSecs,
-		MustContain: WhoIsHiringString,
+		Context:   ctx,
+		Status:    status,
+		ModeForce: force,
+		StoryID:   0,
+		TTLSec:    config.GetConfig().Cache.TTLSecs,
+		Source:    source,
 	}
 	go FetchAsync(fo)
 	go func() {
@@ -1329,6 +1457,230 @@ func actionBrowseStories() {
 	}
 }
 
+func actionBrowseRejects() {
+	if showingModal || displayOptions.curStory.Id == 0 {
+		return
+	}
+	rejects, err := db.GetAllRejectsByStoryId(displayOptions.curStory.Id)
+	maybePanic(err)
+	if len(rejects) == 0 {
+		showModalTextView(3, 50, "\n No rejected comments for this month.", " Rejects ")
+		return
+	}
+	showingModal = true
+	rows := 30
+	if screenSize.Y-6 < rows {
+		rows = max(screenSize.Y-6, 15)
+	}
+	cols := 100
+	if screenSize.X-10 < cols {
+		cols = max(screenSize.X-10, 60)
+	}
+	bgColor := tcell.GetColor(curTheme.UI.ModalNormal.Bg)
+	const browseRejectsPageName = "browseRejects"
+	const promotePageName = "rejectPromote"
+
+	rejectText := tview.NewTextView(). // tv attrs
+						SetDynamicColors(true).
+						SetRegions(true)
+	rejectText. // Box attrs
+			SetBackgroundColor(tcell.GetColor(curTheme.JobBody.FrameBackground.Bg))
+
+	rejectList := tview.NewList(). // list attrs
+					ShowSecondaryText(false).
+					SetWrapAround(false).
+					SetSelectedBackgroundColor(tcell.GetColor(curTheme.CompanyList.Colors.SelectedItemBackground.Bg))
+	rejectList. // Box attrs
+			SetBackgroundColor(bgColor)
+	rejectList.SetHighlightFullLine(true)
+	for _, r := range rejects {
+		rejectList.AddItem(
+			fmt.Sprintf("%s%d %s%s", curTheme.UI.ModalHighlight.AsTag(), r.Id, curTheme.UI.ModalNormal.AsTag(), r.Reason),
+			"", 0, nil,
+		)
+	}
+	rejectList.SetChangedFunc(func(index int, mainText string, secondaryText string, shortcut rune) {
+		// format it like a job so it's easy to tell whether it is one
+		dj := newDisplayJob(&db.Job{Id: rejects[index].Id, Text: rejects[index].Text, Interested: true})
+		rejectText.SetText(dj.DisplayText).ScrollToBeginning()
+	})
+
+	promoteInput := tview.NewInputField(). // input attrs
+						SetLabel("Company name: ").
+						SetFieldWidth(maxCompanyNameLength).
+						SetLabelStyle(curTheme.UI.ModalNormal.AsTCellStyle()).
+						SetFieldStyle(curTheme.UI.ModalHighlight.AsTCellStyle())
+	promoteInput. // Box attrs
+			SetBorder(true).
+			SetBorderColor(tcell.GetColor(curTheme.UI.FocusBorder.Fg)).
+			SetTitle(curTheme.UI.ModalTitle.AsTag() + " Promote to job ").
+			SetTitleAlign(tview.AlignLeft).
+			SetBackgroundColor(bgColor)
+	promoteInput.SetDoneFunc(func(key tcell.Key) {
+		switch key {
+		case tcell.KeyEnter:
+			job, err := PromoteReject(rejects[rejectList.GetCurrentItem()].Id, promoteInput.GetText())
+			if err != nil {
+				promoteInput.SetTitle(curTheme.UI.ModalTitle.AsTag() + " " + tview.Escape(err.Error()) + " ")
+				return
+			}
+			pages.RemovePage(promotePageName)
+			pages.RemovePage(browseRejectsPageName)
+			story := displayOptions.curStory
+			reset()
+			displayOptions.curStory = story
+			loadList(job.Id)
+		case tcell.KeyEscape:
+			pages.RemovePage(promotePageName)
+			tvApp.SetFocus(rejectList)
+		}
+	})
+
+	rejectList.SetSelectedFunc(func(i int, s string, s2 string, r rune) {
+		promoteInput.SetText("")
+		pages.AddPage(promotePageName, makeModal(promoteInput, 60, 3), true, true)
+		tvApp.SetFocus(promoteInput)
+	})
+	rejectList.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Rune() {
+		case 'j':
+			return tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)
+		case 'k':
+			return tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)
+		}
+		switch event.Key() {
+		case tcell.KeyEscape:
+			pages.RemovePage(browseRejectsPageName)
+			showingModal = false
+			return nil
+		case tcell.KeyCtrlD:
+			row, col := rejectText.GetScrollOffset()
+			rejectText.ScrollTo(row+pageScrollAmount, col)
+			return nil
+		case tcell.KeyCtrlU:
+			row, col := rejectText.GetScrollOffset()
+			rejectText.ScrollTo(max(row-pageScrollAmount, 0), col)
+			return nil
+		}
+		return event
+	})
+
+	rejectFlex := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(rejectList, min(len(rejects), rows/3), 0, true).
+		AddItem(rejectText, 0, 1, false)
+	rejectFrame := tview.NewFrame(rejectFlex). //frame attrs
+							AddText(
+			"  enter to promote to a job, ctrl-d / ctrl-u to scroll  ",
+			true, tview.AlignCenter, 0,
+		).
+		SetBorders(1, 0, 0, 0, 0, 0)
+	rejectFrame. // box attrs
+			SetBorder(true).
+			SetBorderColor(tcell.GetColor(curTheme.UI.FocusBorder.Fg)).
+			SetBackgroundColor(bgColor).
+			SetTitle(curTheme.UI.ModalTitle.AsTag() + " Rejected Comments ").
+			SetTitleAlign(tview.AlignLeft)
+
+	pages.AddPage(browseRejectsPageName, makeModal(rejectFrame, cols, rows), true, true)
+	rejectList.SetCurrentItem(0)
+	dj := newDisplayJob(&db.Job{Id: rejects[0].Id, Text: rejects[0].Text, Interested: true})
+	rejectText.SetText(dj.DisplayText)
+	tvApp.SetFocus(rejectList)
+}
+
+// actionShowScoreBreakdown lists the rules which matched the selected job, with their points and what they matched
+func actionShowScoreBreakdown() {
+	if len(displayJobs) == 0 {
+		return
+	}
+	job := displayJobs[companyList.GetCurrentItem()].Job
+	normal := curTheme.UI.ModalNormal.AsTag()
+	hl := curTheme.UI.ModalHighlight.AsTag()
+	positive := sanitview.MergeTviewStyles(curTheme.UI.ModalNormal, curTheme.JobBody.PositiveHit).AsTag()
+	negative := sanitview.MergeTviewStyles(curTheme.UI.ModalNormal, curTheme.JobBody.NegativeHit).AsTag()
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s\n Score: %s%d%s\n", normal, hl, job.Score, normal))
+	if len(job.Why) > 0 {
+		sb.WriteString(" Why: " + tview.Escape(strings.Join(job.Why, ", ")) + "\n")
+	}
+	if len(job.WhyNot) > 0 {
+		sb.WriteString(" Why not: " + tview.Escape(strings.Join(job.WhyNot, ", ")) + "\n")
+	}
+	sb.WriteString("\n")
+	total := 0
+	for _, hit := range job.RuleHits {
+		total += hit.Points
+	}
+	switch {
+	case total != job.Score:
+		// e.g. it was scored by an older version which didn't record the hits
+		sb.WriteString(" This breakdown is out of date.  Press " + hl + "s" + normal + " to re-score.\n\n")
+	case len(job.RuleHits) == 0:
+		sb.WriteString(" No rules matched this job.\n")
+	}
+	plain := plaintext.FromHTML(job.Text)
+	for _, hit := range job.RuleHits {
+		style := positive
+		if hit.Points < 0 {
+			style = negative
+		}
+		sb.WriteString(fmt.Sprintf(
+			"%s%+4d%s  rule %d: %s\n", style, hit.Points, normal, hit.RuleIndex+1, tview.Escape(hit.Rule),
+		))
+		for _, m := range hit.Matches {
+			if m[1] > len(plain) {
+				continue // the text changed since it was scored
+			}
+			sb.WriteString("       \"" + tview.Escape(plain[m[0]:m[1]]) + "\"\n")
+		}
+	}
+	rows := max(screenSize.Y-10, 15)
+	cols := min(max(screenSize.X-10, 60), 100)
+	showModalTextView(rows, cols, sb.String(), " Score Breakdown ")
+}
+
+// actionShowChanges shows a diff between the selected job's current text and the version before it was last edited
+func actionShowChanges() {
+	if len(displayJobs) == 0 {
+		return
+	}
+	job := displayJobs[companyList.GetCurrentItem()].Job
+	revisions, err := db.GetRevisionsByJobId(job.Id)
+	maybePanic(err)
+	if len(revisions) < 2 {
+		showModalTextView(3, 50, "\n This job hasn't been edited.", " Changes ")
+		return
+	}
+	prev := revisions[len(revisions)-2]
+	cur := revisions[len(revisions)-1]
+	normal := curTheme.UI.ModalNormal.AsTag()
+	deleted := sanitview.MergeTviewStyles(
+		curTheme.UI.ModalNormal, curTheme.JobBody.NegativeHit, &sanitview.TViewStyle{Attrs: "s"},
+	).AsTag()
+	inserted := sanitview.MergeTviewStyles(curTheme.UI.ModalNormal, curTheme.JobBody.PositiveHit).AsTag()
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(
+		"%s\n %d versions.  Changes between %s and %s:\n\n",
+		normal,
+		len(revisions),
+		prev.FetchedGoTime.Format("Jan 2 15:04"),
+		cur.FetchedGoTime.Format("Jan 2 15:04"),
+	))
+	for _, op := range textdiff.Words(plaintext.FromHTML(prev.Text), plaintext.FromHTML(cur.Text)) {
+		switch op.Kind {
+		case textdiff.Equal:
+			sb.WriteString(tview.Escape(op.Text))
+		case textdiff.Delete:
+			sb.WriteString(deleted + tview.Escape(op.Text) + normal)
+		case textdiff.Insert:
+			sb.WriteString(inserted + tview.Escape(op.Text) + normal)
+		}
+	}
+	rows := max(screenSize.Y-10, 15)
+	cols := min(max(screenSize.X-10, 60), 100)
+	showModalTextView(rows, cols, sb.String(), " Changes ")
+}
+
 func actionRescore() {
 	if !weHaveData() {
 		return
@@ -1366,6 +1718,8 @@ func actionRescore() {
 
 		err = config.Reload()
 		maybePanic(err)
+		err = ConfigureNetwork()
+		maybePanic(err)
 		err = scoring.ReloadRules()
 		maybePanic(err)
 		num, err := ReScore(displayOptions.curStory.Id)
@@ -1398,6 +1752,7 @@ func loadList(prevDisplayJobID int) {
 	displayJobs = []*DisplayJob{}
 	displayStats.numBelowThreshold = 0
 	displayStats.numUninterested = 0
+	displayStats.numWithdrawn = 0
 	displayStats.numHidden = 0
 	// rebuild list and try to find previously-selected item by id
 	newDJIndex := -1
@@ -1409,6 +1764,9 @@ func loadList(prevDisplayJobID int) {
 		if !dj.Interested {
 			displayStats.numUninterested++
 		}
+		if dj.WithdrawnTime != 0 {
+			displayStats.numWithdrawn++
+		}
 		if dj.Hidden {
 			displayStats.numHidden++
 			continue
@@ -1511,13 +1869,15 @@ func getStatsText(availableWidth int) string {
 
 	// details
 
-	var btLabel, uLabel string
+	var btLabel, uLabel, wLabel string
 	if condensed {
 		btLabel = "<Th"
 		uLabel = "Un"
+		wLabel = "Wd"
 	} else {
 		btLabel = " Below Threshold "
 		uLabel = " Uninterested"
+		wLabel = " Withdrawn"
 	}
 	builder.WriteString(" (")
 	moreStats := []string{}
@@ -1536,6 +1896,9 @@ func getStatsText(availableWidth int) string {
 		)
 		moreStats = append(moreStats, uText)
 	}
+	if displayStats.numWithdrawn > 0 && !displayOptions.showWithdrawn {
+		moreStats = append(moreStats, fmt.Sprintf("%d%s", displayStats.numWithdrawn, wLabel))
+	}
 	if condensed {
 		builder.WriteString(strings.Join(moreStats, ","))
 	} else {
@@ -1,23 +1,29 @@
 package app
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
+	"github.com/mwinters0/hnjobs/config"
 	"github.com/mwinters0/hnjobs/db"
+	"github.com/mwinters0/hnjobs/gazetteer"
 	"github.com/mwinters0/hnjobs/hn"
+	"github.com/mwinters0/hnjobs/jobheader"
+	"github.com/mwinters0/hnjobs/salary"
 	"github.com/mwinters0/hnjobs/scoring"
-	"html"
+	"github.com/mwinters0/hnjobs/techstack"
+	"github.com/mwinters0/hnjobs/workmode"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
+	"unicode/utf8"
 )
 
-var existingJobs map[int]*db.Job // key is job ID
-var numNewJobsFetched atomic.Int32
-var numUpdatedJobsFetched atomic.Int32
-var numCommentsFetched atomic.Int32
+// How many passes we make over comments which fail to fetch.  Note that each pass already includes hn's own retries.
+const maxFetchPasses = 3
 
 type UpdateType = int
 
@@ -28,6 +34,7 @@ const (
 	UpdateTypeFatal
 	UpdateTypeBadComment
 	UpdateTypeJobFetched
+	UpdateTypeJobWithdrawn
 	UpdateTypeDone // value is newJobs + updatedJobs
 )
 
@@ -36,94 +43,185 @@ type FetchStatusUpdate struct {
 	Message    string
 	Value      int // either new job score or number of jobs fetched on completion
 	Error      error
+	JobID      int // the comment or job this is about, if any
 }
 
-const WhoIsHiringString string = "Who is hiring?"
-
 // todo? make this config-driven
 const maxCompanyNameLength = 30 // is not the same const in app/browse
 
 type FetchOptions struct {
-	Context     context.Context
-	Status      chan<- FetchStatusUpdate
-	ModeForce   bool
-	StoryID     int // fetch latest if 0
-	TTLSec      int64
-	MustContain string // typically "Who's Hiring"
+	Context   context.Context
+	Status    chan<- FetchStatusUpdate
+	ModeForce bool
+	// ModeIncremental only fetches new jobs and the jobs which HN reports as recently changed, ignoring the TTL.
+	ModeIncremental bool
+	StoryID         int    // fetch latest if 0
+	TTLSec          int64  // jobs fetched longer ago than this are refetched
+	MaxRefresh      int    // max number of TTL-expired jobs to refetch per run, oldest first; 0 for no limit
+	Source          Source // whoishiring if nil
 }
 
 func genericStatus(s string, c chan<- FetchStatusUpdate) {
-	c <- FetchStatusUpdate{UpdateTypeGeneric, s, 0, nil}
+	c <- FetchStatusUpdate{UpdateTypeGeneric, s, 0, nil, 0}
+}
+
+// defaults for the FetchConfig tuning settings
+const (
+	defaultFetchWorkers    = 3
+	defaultFetchProcessors = 3
+	defaultFetchBatchSize  = 20
+)
+
+// Fetcher fetches and scores one story's jobs.  All of its state is its own, so several can run at the same time (e.g.
+// for different stories), but each Fetcher can only Run once.
+type Fetcher struct {
+	fo         FetchOptions
+	Workers    int // how many comments to fetch at once
+	Processors int // how many goroutines parse, score and store the comments
+	QueueDepth int // how many items can wait between each stage of the pipeline
+	BatchSize  int // how many jobs each processor stores per DB transaction
+
+	existingJobs          map[int]*db.Job // key is job ID.  Read-only once the workers start.
+	numNewJobsFetched     atomic.Int32
+	numUpdatedJobsFetched atomic.Int32
+	numCommentsFetched    atomic.Int32
+	numWithdrawnJobs      atomic.Int32
+	// Comments which failed to fetch with a temporary error, to be re-queued.
+	failedCommentIDs      []int
+	failedCommentIDsMutex sync.Mutex
+	ran                   atomic.Bool
+	// for FetchTimings
+	httpNanos    atomic.Int64
+	scoringNanos atomic.Int64
+	dbNanos      atomic.Int64
+	numDBBatches atomic.Int32
+}
+
+// FetchResult is what a Fetcher did.  It's filled in even if the fetch was cancelled or failed part-way.
+type FetchResult struct {
+	StoryID         int // 0 if we failed before finding the story
+	NewJobs         int
+	UpdatedJobs     int
+	Comments        int // how many comments we fetched
+	WithdrawnJobs   int
+	SkippedComments []int // IDs which repeatedly failed to fetch
+	Cancelled       bool
+	Err             error // set if the fetch failed
+	Timings         FetchTimings
+}
+
+// FetchTimings are where the time went.  Everything but Total is summed across the workers, so they can add up to more
+// than Total.
+type FetchTimings struct {
+	Total     time.Duration
+	HTTP      time.Duration // fetching comments; not including finding the story
+	Scoring   time.Duration // parsing and scoring jobs
+	DB        time.Duration // storing jobs
+	DBBatches int           // how many transactions the jobs were stored in
 }
 
+// TotalJobs is the number of new and updated jobs, which is also the Value of the final UpdateTypeDone status
+func (fr *FetchResult) TotalJobs() int {
+	return fr.NewJobs + fr.UpdatedJobs
+}
+
+// NewFetcher makes a Fetcher tuned according to the config file
+func NewFetcher(fo FetchOptions) *Fetcher {
+	if fo.Source == nil {
+		fo.Source = NewWhoIsHiringSource(nil)
+	}
+	fc := config.GetConfig().Fetch
+	return &Fetcher{
+		fo:           fo,
+		Workers:      cmp.Or(fc.Workers, defaultFetchWorkers),
+		Processors:   cmp.Or(fc.Processors, defaultFetchProcessors),
+		QueueDepth:   fc.QueueDepth,
+		BatchSize:    cmp.Or(fc.BatchSize, defaultFetchBatchSize),
+		existingJobs: make(map[int]*db.Job),
+	}
+}
+
+// FetchAsync runs a new Fetcher.  See Fetcher.Run.
 func FetchAsync(fo FetchOptions) {
+	NewFetcher(fo).Run()
+}
+
+// Run does the fetch, sending progress to the Status channel.  The last update is either UpdateTypeDone or
+// UpdateTypeFatal, after which the channel is closed.
+func (f *Fetcher) Run() *FetchResult {
+	if f.ran.Swap(true) {
+		panic(errors.New("Fetcher.Run called twice"))
+	}
 	var err error
-	existingJobs = make(map[int]*db.Job) // cache for TTL checking
-	numNewJobsFetched.Store(0)
-	numUpdatedJobsFetched.Store(0)
-	numCommentsFetched.Store(0)
+	fo := f.fo
 	storyId := fo.StoryID // if we fetch latest then this val will change
+	result := &FetchResult{}
+	startTime := time.Now()
 
-	notifyCompletion := func(msg string, v int, e error, fatal bool) {
+	notifyCompletion := func(msg string, e error, fatal bool) *FetchResult {
 		// Just a single place to close() on completion
+		result.NewJobs = int(f.numNewJobsFetched.Load())
+		result.UpdatedJobs = int(f.numUpdatedJobsFetched.Load())
+		result.Comments = int(f.numCommentsFetched.Load())
+		result.WithdrawnJobs = int(f.numWithdrawnJobs.Load())
+		result.Cancelled = fo.Context.Err() != nil
+		result.Timings = FetchTimings{
+			Total:     time.Since(startTime),
+			HTTP:      time.Duration(f.httpNanos.Load()),
+			Scoring:   time.Duration(f.scoringNanos.Load()),
+			DB:        time.Duration(f.dbNanos.Load()),
+			DBBatches: int(f.numDBBatches.Load()),
+		}
 		if fatal {
-			fo.Status <- FetchStatusUpdate{UpdateTypeFatal, msg, v, e}
+			result.Err = e
+			if result.Err == nil {
+				result.Err = errors.New(msg)
+			}
+			fo.Status <- FetchStatusUpdate{UpdateTypeFatal, msg, result.TotalJobs(), e, 0}
 		} else {
-			fo.Status <- FetchStatusUpdate{UpdateTypeDone, msg, v, e}
+			fo.Status <- FetchStatusUpdate{UpdateTypeDone, msg, result.TotalJobs(), e, 0}
 		}
 		close(fo.Status)
+		return result
 	}
 
 	isNewStory := false
 	var apiStory *hn.Story
 	if storyId == 0 {
 		// get latest
-		genericStatus("Fetching job stories...", fo.Status)
-		submissions, err := hn.FetchSubmissions(fo.Context, "whoishiring")
-		if err != nil {
-			notifyCompletion("Error fetching job stories", 0, err, true)
-			return
-		}
-		genericStatus(fmt.Sprintf("Found %d job stories...", len(submissions)), fo.Status)
-		if fo.MustContain != "" {
-			genericStatus(fmt.Sprintf("Searching for most-recent '%s' story...", fo.MustContain), fo.Status)
-			for i := range submissions {
-				s, err := hn.FetchStory(fo.Context, submissions[i])
-				if err != nil {
-					notifyCompletion(fmt.Sprintf("Failed to retrieve job story %d from API.", submissions[i]), 0, err, true)
-					return
-				}
-				if strings.Contains(s.Title, fo.MustContain) {
-					apiStory = s
-					break
-				}
-			}
-			if apiStory == nil {
-				notifyCompletion(fmt.Sprintf("Couldn't find a job story matching '%s'", fo.MustContain), 0, err, true)
-				return
-			}
-		} else {
-			apiStory, err = hn.FetchStory(fo.Context, submissions[0])
+		genericStatus(fmt.Sprintf("Searching for the most-recent %s story...", fo.Source.Name()), fo.Status)
+		for s, err := range fo.Source.DiscoverStories(fo.Context) {
 			if err != nil {
-				notifyCompletion(fmt.Sprintf("Failed to retrieve job story %d from API.", submissions[0]), 0, err, true)
-				return
+				return notifyCompletion("Error finding job stories", err, true)
 			}
+			apiStory = s
+			break
+		}
+		if apiStory == nil {
+			return notifyCompletion(fmt.Sprintf("Couldn't find a %s story", fo.Source.Name()), nil, true)
 		}
 		storyId = apiStory.Id
+		apiStory.Kids, err = fo.Source.ListItemIDs(fo.Context, apiStory)
+		if err != nil {
+			return notifyCompletion(fmt.Sprintf("Failed to retrieve job story %d from API.", storyId), err, true)
+		}
 	} else {
-		apiStory, err = hn.FetchStory(fo.Context, storyId)
+		// through the source, so that we use the configured backend and don't file e.g. a freelancer story as a
+		// whoishiring one
+		apiStory, err = fo.Source.FetchStory(fo.Context, storyId)
+		if errors.Is(err, ErrNotInSource) {
+			return notifyCompletion(fmt.Sprintf("Story %d isn't a %s story.", storyId, fo.Source.Name()), err, true)
+		}
 		if err != nil {
-			notifyCompletion("Failed to retrieve job story from API.", 0, err, true)
-			return
+			return notifyCompletion("Failed to retrieve job story from API.", err, true)
 		}
 	}
+	result.StoryID = storyId
+	apiStory.Source = fo.Source.Name()
 
-	// get story comment IDs
-
-	dbStory, err := db.GetStoryById(storyId)
+	_, err = db.GetStoryById(storyId)
 	if err != nil && !errors.Is(err, db.ErrNoResults) {
-		notifyCompletion("Failure checking DB for existing story.", 0, err, true)
-		return
+		return notifyCompletion("Failure checking DB for existing story.", err, true)
 	}
 	if errors.Is(err, db.ErrNoResults) {
 		isNewStory = true
@@ -132,21 +230,21 @@ func FetchAsync(fo FetchOptions) {
 			fmt.Sprintf("Found NEW job story: \"%s\" (%d top-level comments)", apiStory.Title, len(apiStory.Kids)),
 			apiStory.Id,
 			nil,
+			0,
 		}
 		// We don't want to set fetched_time in the DB until after we've completed the fetch
 		apiStory.FetchedTime = 0
 		apiStory.FetchedGoTime = time.Unix(0, 0)
 		err = db.UpsertStory(apiStory)
 		if err != nil {
-			notifyCompletion("Failed to upsert story.", 0, err, true)
-			return
+			return notifyCompletion("Failed to upsert story.", err, true)
 		}
 	} else {
 		genericStatus(fmt.Sprintf("Most-recent job story (%d) was previously cached", storyId), fo.Status)
 	}
 
-	// if we're using story-based ttl, then we only have two modes: fetch all (ttl expired), or fetch new.
-	// However, we want to defer updating the story fetch time until after fetch is complete.
+	// TTL is per-job: we fetch new comments, recently-changed comments, and jobs which have individually gone stale.
+	// We still record the story's fetched_time after the fetch completes, but only as "last fetched".
 
 	var commentIDsToFetch []int
 	if isNewStory {
@@ -156,12 +254,25 @@ func FetchAsync(fo FetchOptions) {
 		// When fetching a job, it might be an update of an existing job.  We then want to set unread while
 		// preserving the rest of the user-created state.
 		jobs, err := db.GetAllJobsByStoryId(storyId, db.OrderNone)
-		if err != nil && errors.Is(err, db.ErrNoResults) {
-			notifyCompletion("Failed to fetch existing jobs from the DB", 0, err, true)
-			return
+		if err != nil && !errors.Is(err, db.ErrNoResults) {
+			return notifyCompletion("Failed to fetch existing jobs from the DB", err, true)
 		}
 		for _, job := range jobs {
-			existingJobs[job.Id] = job
+			f.existingJobs[job.Id] = job
+		}
+		// Deleted comments without replies disappear from the story's kids
+		kids := make(map[int]bool, len(apiStory.Kids))
+		for _, id := range apiStory.Kids {
+			kids[id] = true
+		}
+		for _, job := range jobs {
+			if kids[job.Id] || job.WithdrawnTime != 0 {
+				continue
+			}
+			err = f.withdrawJob(job, fo.Status)
+			if err != nil {
+				return notifyCompletion("Failed to upsert job into DB!", err, true)
+			}
 		}
 		//decide what we're fetching
 		if fo.ModeForce {
@@ -169,117 +280,129 @@ func FetchAsync(fo FetchOptions) {
 			commentIDsToFetch = apiStory.Kids
 			genericStatus("ModeForce-fetching all top-level comments...", fo.Status)
 		} else {
-			// check story TTL
-			age := time.Now().UTC().Unix() - dbStory.FetchedTime
-			if age > fo.TTLSec {
-				// expired TTL - fetch all
-				genericStatus(
-					fmt.Sprintf("Story TTL is expired (age %d, TTL is %d) - fetching all", age, fo.TTLSec),
-					fo.Status,
-				)
-				commentIDsToFetch = apiStory.Kids
-			} else {
-				// only fetch new
-				genericStatus(
-					fmt.Sprintf("Story TTL is not expired (age %d, TTL is %d) - only fetching new", age, fo.TTLSec),
-					fo.Status,
-				)
-				for _, id := range apiStory.Kids {
-					if _, ok := existingJobs[id]; !ok {
-						commentIDsToFetch = append(commentIDsToFetch, id)
-					}
-				}
-				existingJobs = make(map[int]*db.Job) // free this memory / speed up this search
-			}
+			commentIDsToFetch = f.refreshCommentIDs(apiStory)
 		}
 	}
 
 	// pipeline: produce comment IDs -> fetch comments -> score and store -> done
 	//
-	// producer
-	commentIDs := make(chan int)
-	produce := func() {
-		for _, commentID := range commentIDsToFetch {
+	// runPass returns the result if the fetch ended (cancelled or fatal) and we've already notified completion.
+	runPass := func(ids []int) *FetchResult {
+		// producer
+		commentIDs := make(chan int, f.QueueDepth)
+		produce := func() {
+			for _, commentID := range ids {
+				select {
+				case <-fo.Context.Done():
+					close(commentIDs)
+					return
+				default:
+					commentIDs <- commentID
+				}
+			}
+			close(commentIDs)
+		}
+		// fetchers
+		numWorkers := max(f.Workers, 1)
+		numProcessors := max(f.Processors, 1)
+		comments := make(chan *hn.Comment, f.QueueDepth)
+		workerUpdates := make(chan FetchStatusUpdate)
+		fwg := sync.WaitGroup{}
+		fwg.Add(numWorkers)
+		for i := 0; i < numWorkers; i++ {
+			go f.commentFetcher(&fwg, commentIDs, comments, workerUpdates)
+		}
+		// processors
+		pwg := sync.WaitGroup{}
+		pwg.Add(numProcessors)
+		for i := 0; i < numProcessors; i++ {
+			go f.commentProcessor(&pwg, comments, workerUpdates)
+		}
+		// done waiter
+		workersDone := make(chan int)
+		go func() {
+			fwg.Wait()
+			close(comments)
+			pwg.Wait()
+			close(workersDone)
+		}()
+
+		go produce()
+
+		// Wait for one of the following:
+		// 1. cancellation
+		// 2. all comments to be finished
+		// 3. a fatal error from a worker (cancel all other workers)
+		for {
 			select {
-			case <-fo.Context.Done():
-				close(commentIDs)
-				return
-			default:
-				commentIDs <- commentID
+			case <-fo.Context.Done(): //1
+				return notifyCompletion("Fetching cancelled", nil, false)
+			case <-workersDone: //2
+				return nil
+			case wStatus := <-workerUpdates: //this channel should never close
+				if wStatus.UpdateType == UpdateTypeFatal { //3
+					return notifyCompletion(wStatus.Message, wStatus.Error, true)
+				}
+				fo.Status <- wStatus
 			}
 		}
-		close(commentIDs)
 	}
-	// fetchers
-	numWorkers := 3
-	comments := make(chan *hn.Comment)
-	workerUpdates := make(chan FetchStatusUpdate)
-	fwg := sync.WaitGroup{}
-	fwg.Add(numWorkers)
-	for i := 0; i < numWorkers; i++ {
-		go commentFetcher(fo.Context, &fwg, commentIDs, comments, workerUpdates)
+
+	idsThisPass := commentIDsToFetch
+	var skippedIDs []int
+	for pass := 1; ; pass++ {
+		if res := runPass(idsThisPass); res != nil {
+			return res
+		}
+		f.failedCommentIDsMutex.Lock()
+		failed := f.failedCommentIDs
+		f.failedCommentIDs = nil
+		f.failedCommentIDsMutex.Unlock()
+		if len(failed) == 0 {
+			break
+		}
+		if pass == maxFetchPasses {
+			skippedIDs = failed
+			break
+		}
+		genericStatus(fmt.Sprintf("Re-queueing %d comments which failed to fetch...", len(failed)), fo.Status)
+		idsThisPass = failed
 	}
-	// processors
-	pwg := sync.WaitGroup{}
-	pwg.Add(numWorkers)
-	for i := 0; i < numWorkers; i++ {
-		go commentProcessor(fo.Context, &pwg, comments, workerUpdates)
+	for _, id := range skippedIDs {
+		fo.Status <- FetchStatusUpdate{
+			UpdateTypeNonFatalErr,
+			fmt.Sprintf("Giving up on comment id %d after %d attempts.", id, maxFetchPasses),
+			0,
+			nil,
+			id,
+		}
 	}
-	// done waiter
-	workersDone := make(chan int)
-	go func() {
-		fwg.Wait()
-		close(comments)
-		pwg.Wait()
-		close(workersDone)
-	}()
-
-	go produce()
-
-	totalJobsFetched := func() int {
-		return int(numNewJobsFetched.Load() + numUpdatedJobsFetched.Load())
+
+	// update the fetched_time on the story for TTL
+	apiStory.FetchedGoTime = time.Now()
+	apiStory.FetchedTime = apiStory.FetchedGoTime.UTC().Unix()
+	err = db.UpsertStory(apiStory)
+	if err != nil {
+		return notifyCompletion("Failed to upsert story.", err, true)
 	}
-	// Wait for one of the following:
-	// 1. cancellation
-	// 2. all comments to be finished
-	// 3. a fatal error from a worker (cancel all other workers)
-	for {
-		select {
-		case <-fo.Context.Done(): //1
-			notifyCompletion("Fetching cancelled", totalJobsFetched(), nil, false)
-			return
-		case <-workersDone: //2
-			// update the fetched_time on the story for TTL
-			apiStory.FetchedGoTime = time.Now()
-			apiStory.FetchedTime = apiStory.FetchedGoTime.UTC().Unix()
-			err = db.UpsertStory(apiStory)
-			if err != nil {
-				notifyCompletion("Failed to upsert story.", totalJobsFetched(), err, true)
-				return
-			}
-			notifyCompletion(
-				fmt.Sprintf(
-					"Done. Fetched %d new jobs, %d updated jobs (%d comments).",
-					numNewJobsFetched.Load(),
-					numUpdatedJobsFetched.Load(),
-					numCommentsFetched.Load(),
-				),
-				int(numNewJobsFetched.Load()+numUpdatedJobsFetched.Load()),
-				nil,
-				false,
-			)
-			return
-		case wStatus := <-workerUpdates: //this channel should never close
-			if wStatus.UpdateType == UpdateTypeFatal { //3
-				notifyCompletion(wStatus.Message, wStatus.Value, wStatus.Error, true)
-				return
-			}
-			fo.Status <- wStatus
-		}
+	msg := fmt.Sprintf(
+		"Done. Fetched %d new jobs, %d updated jobs (%d comments).",
+		f.numNewJobsFetched.Load(),
+		f.numUpdatedJobsFetched.Load(),
+		f.numCommentsFetched.Load(),
+	)
+	if f.numWithdrawnJobs.Load() > 0 {
+		msg += fmt.Sprintf(" %d jobs were withdrawn.", f.numWithdrawnJobs.Load())
 	}
+	if len(skippedIDs) > 0 {
+		msg += fmt.Sprintf(" Skipped %d comments which could not be fetched.", len(skippedIDs))
+	}
+	result.SkippedComments = skippedIDs
+	return notifyCompletion(msg, nil, false)
 }
 
-func commentFetcher(ctx context.Context, wg *sync.WaitGroup, commentIDs <-chan int, comments chan<- *hn.Comment, status chan<- FetchStatusUpdate) {
+func (f *Fetcher) commentFetcher(wg *sync.WaitGroup, commentIDs <-chan int, comments chan<- *hn.Comment, status chan<- FetchStatusUpdate) {
+	ctx := f.fo.Context
 	for {
 		select {
 		case <-ctx.Done():
@@ -292,24 +415,37 @@ func commentFetcher(ctx context.Context, wg *sync.WaitGroup, commentIDs <-chan i
 				wg.Done()
 				return
 			}
-			c, err := hn.FetchComment(ctx, i)
+			start := time.Now()
+			c, err := f.fo.Source.FetchItem(ctx, i)
+			f.httpNanos.Add(int64(time.Since(start)))
 			if err != nil {
+				if ctx.Err() != nil {
+					continue
+				}
+				msg := fmt.Sprintf("Failed to fetch comment id %d from API! Ignoring.", i)
+				if hn.IsTemporary(err) {
+					msg = fmt.Sprintf("Failed to fetch comment id %d from API, will re-queue.", i)
+					f.failedCommentIDsMutex.Lock()
+					f.failedCommentIDs = append(f.failedCommentIDs, i)
+					f.failedCommentIDsMutex.Unlock()
+				}
 				status <- FetchStatusUpdate{
 					UpdateTypeNonFatalErr,
-					fmt.Sprintf("Failed to fetch comment id %d from API! Ignoring.", i),
+					msg,
 					0,
 					err,
+					i,
 				}
-				// TODO retry?  backoff?
 				continue
 			}
-			numCommentsFetched.Add(1)
-			if len(c.Text) == 0 {
+			f.numCommentsFetched.Add(1)
+			if len(c.Text) == 0 && !c.Deleted && !c.Dead {
 				status <- FetchStatusUpdate{
 					UpdateTypeBadComment,
 					fmt.Sprintf("Got empty comment id %d from API, ignoring", i),
 					0,
 					errors.New("empty"),
+					i,
 				}
 				continue
 			}
@@ -319,46 +455,141 @@ func commentFetcher(ctx context.Context, wg *sync.WaitGroup, commentIDs <-chan i
 }
 
 // commentProcessor converts a hn Comment to a job, scores it, and stores it in the DB.
-func commentProcessor(ctx context.Context, wg *sync.WaitGroup, comments <-chan *hn.Comment, status chan<- FetchStatusUpdate) {
+func (f *Fetcher) commentProcessor(wg *sync.WaitGroup, comments <-chan *hn.Comment, status chan<- FetchStatusUpdate) {
+	ctx := f.fo.Context
+	// jobs waiting to be stored in a single transaction, and the status updates to send once they are
+	var batch []*db.Job
+	var batchUpdates []FetchStatusUpdate
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		start := time.Now()
+		err := db.UpsertJobs(batch)
+		f.dbNanos.Add(int64(time.Since(start)))
+		f.numDBBatches.Add(1)
+		batch = nil
+		return err
+	}
+	// flushAndReport returns false if we failed to store the batch, which is fatal
+	flushAndReport := func() bool {
+		updates := batchUpdates
+		batchUpdates = nil
+		err := flush()
+		if err != nil {
+			status <- FetchStatusUpdate{
+				UpdateTypeFatal,
+				fmt.Sprintf("Failed to upsert jobs into DB!"),
+				0,
+				err,
+				0,
+			}
+			return false
+		}
+		for _, u := range updates {
+			status <- u
+		}
+		return true
+	}
 	for {
 		select {
 		case <-ctx.Done():
-			//cancelled
+			//cancelled.  Keep what we've got, but nobody's listening for status any more.
+			_ = flush()
 			wg.Done()
 			return
 		case c, ok := <-comments:
 			if !ok {
 				//EOF
+				flushAndReport()
 				wg.Done()
 				return
 			}
+			if c.Deleted || c.Dead {
+				existingJob, found := f.existingJobs[c.Id]
+				if !found {
+					status <- FetchStatusUpdate{
+						UpdateTypeBadComment,
+						fmt.Sprintf("Bad comment (id %d): deleted or dead", c.Id),
+						0,
+						errors.New("deleted or dead"),
+						c.Id,
+					}
+					continue
+				}
+				if existingJob.WithdrawnTime != 0 {
+					continue
+				}
+				err := f.withdrawJob(existingJob, status)
+				if err != nil {
+					//fatal
+					status <- FetchStatusUpdate{
+						UpdateTypeFatal,
+						fmt.Sprintf("Failed to upsert job into DB!"),
+						0,
+						err,
+						0,
+					}
+					wg.Done()
+					return
+				}
+				continue
+			}
 			if len(c.Text) == 0 {
 				status <- FetchStatusUpdate{
 					UpdateTypeBadComment,
 					fmt.Sprintf("Bad comment (id %d): empty comment", c.Id),
 					0,
 					errors.New("len==0"),
+					c.Id,
 				}
 				continue
 			}
+			header := jobheader.Parse(c.Text)
+			cname, err := getCompanyName(&header, maxCompanyNameLength)
+			var reason string
 			if !strings.Contains(c.Text, "|") {
-				status <- FetchStatusUpdate{
-					UpdateTypeBadComment,
-					fmt.Sprintf("Bad comment (id %d): not a job comment", c.Id),
-					0,
-					errors.New("doesn't contain \"|\""),
-				}
-				continue
+				reason = "not a job comment"
+				err = errors.New("doesn't contain \"|\"")
+			} else if err != nil {
+				reason = "couldn't find company name"
 			}
-			cname, err := getCompanyName(c.Text, maxCompanyNameLength)
-			if err != nil {
-				status <- FetchStatusUpdate{
-					UpdateTypeBadComment,
-					fmt.Sprintf("Bad comment (id %d): couldn't find company name", c.Id),
-					0,
-					err,
+			if reason != "" {
+				if existingJob, found := f.existingJobs[c.Id]; found {
+					// the user promoted it from a reject and told us the company name
+					cname = existingJob.Company
+				} else {
+					status <- FetchStatusUpdate{
+						UpdateTypeBadComment,
+						fmt.Sprintf("Bad comment (id %d): %s", c.Id, reason),
+						0,
+						err,
+						c.Id,
+					}
+					start := time.Now()
+					err = db.UpsertReject(&db.Reject{
+						Id:          c.Id,
+						Parent:      c.Parent,
+						Text:        c.Text,
+						Time:        c.Time,
+						FetchedTime: time.Now().UTC().Unix(),
+						Reason:      fmt.Sprintf("%s: %v", reason, err),
+					})
+					f.dbNanos.Add(int64(time.Since(start)))
+					if err != nil {
+						//fatal
+						status <- FetchStatusUpdate{
+							UpdateTypeFatal,
+							fmt.Sprintf("Failed to upsert reject into DB!"),
+							0,
+							err,
+							0,
+						}
+						wg.Done()
+						return
+					}
+					continue
 				}
-				continue
 			}
 			job, err := newJobFromHNComment(c, cname)
 			if err != nil {
@@ -367,13 +598,22 @@ func commentProcessor(ctx context.Context, wg *sync.WaitGroup, comments <-chan *
 					fmt.Sprintf("Unable to process comment ID %d: %v", c.Id, err),
 					0,
 					err,
+					c.Id,
 				}
 				continue
 			}
+			start := time.Now()
+			annotateJob(job)
 			job.FetchedTime = time.Now().UTC().Unix()
 			score := scoring.ScoreDBComment(job)
+			f.scoringNanos.Add(int64(time.Since(start)))
 			// check existing
-			existingJob, found := existingJobs[c.Id]
+			msg := fmt.Sprintf("New job (%d): [Score %d]", c.Id, score)
+			existingJob, found := f.existingJobs[c.Id]
+			if found && existingJob.WithdrawnTime != 0 {
+				// it's back, and newJobFromHNComment leaves WithdrawnTime as 0
+				msg = fmt.Sprintf("Job no longer withdrawn (%d): [Score %d]", c.Id, score)
+			}
 			if found {
 				// preserve user state
 				job.Applied = existingJob.Applied
@@ -381,34 +621,139 @@ func commentProcessor(ctx context.Context, wg *sync.WaitGroup, comments <-chan *
 				job.Interested = existingJob.Interested
 				if job.Text != existingJob.Text {
 					// text changed since last time we saw it
-					numUpdatedJobsFetched.Add(1)
+					f.numUpdatedJobsFetched.Add(1)
 					job.Read = false
 				} else {
+					if existingJob.WithdrawnTime != 0 {
+						f.numUpdatedJobsFetched.Add(1)
+					}
 					job.Read = existingJob.Read
 				}
 			} else {
-				numNewJobsFetched.Add(1)
+				f.numNewJobsFetched.Add(1)
 			}
-			err = db.UpsertJob(job)
-			if err != nil {
-				//fatal
-				status <- FetchStatusUpdate{
-					UpdateTypeFatal,
-					fmt.Sprintf("Failed to upsert job into DB!"),
-					0,
-					err,
-				}
-				wg.Done()
-				return
-			}
-			status <- FetchStatusUpdate{
+			batch = append(batch, job)
+			batchUpdates = append(batchUpdates, FetchStatusUpdate{
 				UpdateTypeJobFetched,
-				fmt.Sprintf("New job (%d): [Score %d]", c.Id, score),
+				msg,
 				score,
 				nil,
+				c.Id,
+			})
+			if len(batch) >= f.BatchSize {
+				if !flushAndReport() {
+					//fatal
+					wg.Done()
+					return
+				}
+			}
+		}
+	}
+}
+
+// refreshCommentIDs decides what to fetch for a previously-cached story: comments we haven't seen before, the ones
+// HN's updates feed says changed recently, withdrawn jobs which are in the story's kids again, and (unless
+// incremental) jobs whose own fetched_time is older than the TTL, oldest first and capped at fo.MaxRefresh.  It trims
+// existingJobs down to just the jobs being refetched.
+func (f *Fetcher) refreshCommentIDs(story *hn.Story) []int {
+	fo := f.fo
+	var newIDs []int
+	var liveJobs []*db.Job
+	refetchJobs := make(map[int]*db.Job)
+	// Withdrawn jobs which are in the story might be back, e.g. HN gave us a partial list of kids last time.  A
+	// successful fetch clears the withdrawal.
+	var withdrawnIDs []int
+	for _, id := range story.Kids {
+		job, ok := f.existingJobs[id]
+		if !ok {
+			newIDs = append(newIDs, id)
+		} else if job.WithdrawnTime == 0 {
+			liveJobs = append(liveJobs, job)
+		} else {
+			refetchJobs[id] = job
+			withdrawnIDs = append(withdrawnIDs, id)
+		}
+	}
+
+	var changedIDs []int
+	updates, err := hn.FetchUpdates(fo.Context)
+	if err != nil {
+		fo.Status <- FetchStatusUpdate{
+			UpdateTypeNonFatalErr,
+			"Failed to fetch recent updates from API, skipping recently-changed jobs.",
+			0,
+			err,
+			0,
+		}
+	} else {
+		for _, id := range updates.Items {
+			if job, ok := f.existingJobs[id]; ok && job.WithdrawnTime == 0 {
+				refetchJobs[id] = job
+				changedIDs = append(changedIDs, id)
 			}
 		}
 	}
+
+	var staleIDs []int
+	numStale := 0
+	if !fo.ModeIncremental {
+		now := time.Now().UTC().Unix()
+		slices.SortFunc(liveJobs, func(a, b *db.Job) int {
+			return cmp.Compare(a.FetchedTime, b.FetchedTime)
+		})
+		for _, job := range liveJobs {
+			if now-job.FetchedTime <= fo.TTLSec {
+				break // sorted, so the rest are fresh too
+			}
+			if _, ok := refetchJobs[job.Id]; ok {
+				continue // already refetching because it changed
+			}
+			numStale++
+			if fo.MaxRefresh > 0 && len(staleIDs) >= fo.MaxRefresh {
+				continue
+			}
+			refetchJobs[job.Id] = job
+			staleIDs = append(staleIDs, job.Id)
+		}
+	}
+
+	msg := fmt.Sprintf(
+		"Fetching %d new, %d recently-changed and %d withdrawn comments", len(newIDs), len(changedIDs), len(withdrawnIDs),
+	)
+	switch {
+	case fo.ModeIncremental:
+		msg += " (incremental mode, ignoring TTL)"
+	case len(staleIDs) < numStale:
+		msg += fmt.Sprintf(", plus the oldest %d of %d jobs older than the TTL of %ds", len(staleIDs), numStale, fo.TTLSec)
+	default:
+		msg += fmt.Sprintf(", plus %d jobs older than the TTL of %ds", len(staleIDs), fo.TTLSec)
+	}
+	genericStatus(msg, fo.Status)
+
+	f.existingJobs = refetchJobs // free this memory / speed up this search
+	ids := slices.Concat(newIDs, changedIDs, withdrawnIDs)
+	return append(ids, staleIDs...)
+}
+
+// withdrawJob marks a job as withdrawn (deleted, dead, or removed from the story) by the poster.
+func (f *Fetcher) withdrawJob(job *db.Job, status chan<- FetchStatusUpdate) error {
+	job.WithdrawnTime = time.Now().UTC().Unix()
+	job.WithdrawnGoTime = time.Unix(job.WithdrawnTime, 0)
+	start := time.Now()
+	err := db.UpsertJob(job)
+	f.dbNanos.Add(int64(time.Since(start)))
+	if err != nil {
+		return err
+	}
+	f.numWithdrawnJobs.Add(1)
+	status <- FetchStatusUpdate{
+		UpdateTypeJobWithdrawn,
+		fmt.Sprintf("Job withdrawn (%d): %s", job.Id, job.Company),
+		job.Score,
+		nil,
+		job.Id,
+	}
+	return nil
 }
 
 func newJobFromHNComment(c *hn.Comment, companyName string) (*db.Job, error) {
@@ -429,19 +774,33 @@ func newJobFromHNComment(c *hn.Comment, companyName string) (*db.Job, error) {
 	return job, nil
 }
 
-func getCompanyName(text string, maxlen int) (string, error) {
-	if len(text) == 0 {
-		return "", errors.New("text len is 0")
-	}
-	text = html.UnescapeString(text)
-	i := strings.IndexAny(text, "(|<\n")
-	if i < 1 {
+// annotateJob fills in everything we parse out of the job's text.  It must be called before scoring.
+func annotateJob(job *db.Job) {
+	job.Header = jobheader.Parse(job.Text)
+	job.Header.Company = job.Company // might have been truncated
+	job.Salary = salary.Extract(job.Header.Salary, job.Text)
+	job.WorkMode = workmode.Classify(job.Header.Remote, job.Text)
+	job.Places = gazetteer.Locate(job.Text, job.Header.Location, job.Header.Remote)
+	job.Tech = techstack.Find(job.Text)
+}
+
+func getCompanyName(h *jobheader.JobHeader, maxlen int) (string, error) {
+	if h.Company == "" {
 		// Note: some jokers put a URL as the company name, causing this.  We could special-case that, but maybe
 		// just don't work for jokers?  You're welcome.
 		return "", errors.New("no text before first delimiter")
 	}
-	if i > maxlen {
-		i = maxlen
+	return strings.Clone(truncateCompanyName(h.Company, maxlen)), nil
+}
+
+// truncateCompanyName cuts the name down to maxlen bytes, without splitting a multibyte character
+func truncateCompanyName(cname string, maxlen int) string {
+	if len(cname) <= maxlen {
+		return cname
+	}
+	cut := maxlen
+	for cut > 0 && !utf8.RuneStart(cname[cut]) {
+		cut--
 	}
-	return strings.Clone(strings.TrimSpace(text[:i])), nil
+	return strings.TrimSpace(cname[:cut])
 }
@@ -0,0 +1,308 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/adrg/xdg"
+	"github.com/mwinters0/hnjobs/config"
+	"github.com/mwinters0/hnjobs/db"
+	"github.com/mwinters0/hnjobs/hn"
+	"github.com/mwinters0/hnjobs/scoring"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// cassetteItem is a firebase item response for the cassette
+func cassetteItem(t *testing.T, item map[string]any) string {
+	t.Helper()
+	return cassetteLine(t, fmt.Sprintf("/item/%v.json", item["id"]), mustJSON(t, item))
+}
+
+func cassetteLine(t *testing.T, path string, body string) string {
+	t.Helper()
+	return mustJSON(t, map[string]any{
+		"url":    "https://hacker-news.firebaseio.com/v0" + path,
+		"status": 200,
+		"body":   body,
+	})
+}
+
+func mustJSON(t *testing.T, v any) string {
+	t.Helper()
+	j, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(j)
+}
+
+// setupAppTest gives us a fresh DB and the default config, without touching the user's.  rules replaces the default
+// scoring rules if it isn't nil.
+func setupAppTest(t *testing.T, rules []config.ScoringRule) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+	xdg.Reload()
+	t.Cleanup(xdg.Reload)
+	configPath, err := config.GetPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents := config.DefaultConfigFileContents()
+	if rules != nil {
+		var conf config.ConfigObj
+		err = json.Unmarshal(contents, &conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conf.Scoring.Rules = rules
+		contents = []byte(mustJSON(t, conf))
+	}
+	err = os.WriteFile(configPath, contents, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = config.Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = scoring.ReloadRules()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.NewDB(filepath.Join(dir, "hnjobs.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = hn.UseCassette("", hn.CassetteOff)
+	})
+}
+
+func TestFetch(t *testing.T) {
+	setupAppTest(t, nil)
+
+	story := func(kids ...int) map[string]any {
+		return map[string]any{
+			"by":    "whoishiring",
+			"id":    100,
+			"kids":  kids,
+			"time":  1727794816,
+			"title": "Ask HN: Who is hiring? (October 2024)",
+			"type":  "story",
+		}
+	}
+	comment := func(id int, text string) map[string]any {
+		return map[string]any{"by": "someone", "id": id, "parent": 100, "text": text, "time": 1727794900, "type": "comment"}
+	}
+	deleted := comment(104, "")
+	delete(deleted, "text")
+	deleted["deleted"] = true
+	acme := comment(101, "Acme Corp | SRE | Remote | $150k-$200k<p>We use golang and rust.")
+	acmeEdited := comment(101, "Acme Corp | SRE | Remote | $160k-$210k<p>We use golang and rust.")
+	widgets := comment(102, "Widgets Inc | Backend | Onsite NYC<p>Python shop")
+	meta := comment(103, "Just a meta comment with no pipes")
+	gadgets := comment(105, "Gadgets LLC | Frontend | Hybrid Berlin<p>React")
+
+	// Each step is a fetch of the latest story against what the earlier steps left in the DB
+	steps := []struct {
+		name          string
+		story         map[string]any
+		comments      []map[string]any
+		updates       []int // HN's recently-changed items
+		wantNew       int
+		wantUpdated   int
+		wantWithdrawn int
+		wantRejects   []int
+		wantJobs      []int // not withdrawn
+	}{
+		{
+			name:        "new story",
+			story:       story(101, 102, 103, 104),
+			comments:    []map[string]any{acme, widgets, meta, deleted},
+			wantNew:     2,
+			wantRejects: []int{103},
+			wantJobs:    []int{101, 102},
+		},
+		{
+			name:          "edited, withdrawn and new",
+			story:         story(101, 103, 105),
+			comments:      []map[string]any{acmeEdited, meta, gadgets},
+			updates:       []int{101},
+			wantNew:       1,
+			wantUpdated:   1,
+			wantWithdrawn: 1,
+			wantRejects:   []int{103},
+			wantJobs:      []int{101, 105},
+		},
+		{
+			name:        "withdrawn job is back",
+			story:       story(101, 102, 103, 105),
+			comments:    []map[string]any{acmeEdited, widgets, meta, gadgets},
+			wantUpdated: 1,
+			wantRejects: []int{103},
+			wantJobs:    []int{101, 102, 105},
+		},
+	}
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			lines := []string{
+				cassetteLine(t, "/user/whoishiring/submitted.json", "[100]"),
+				cassetteItem(t, step.story),
+				cassetteLine(t, "/updates.json", mustJSON(t, map[string]any{"items": step.updates, "profiles": []string{}})),
+			}
+			for _, c := range step.comments {
+				lines = append(lines, cassetteItem(t, c))
+			}
+			path := filepath.Join(t.TempDir(), "cassette.jsonl")
+			err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = hn.UseCassette(path, hn.CassetteReplay)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			status := make(chan FetchStatusUpdate)
+			go func() {
+				for range status {
+				}
+			}()
+			result := NewFetcher(FetchOptions{
+				Context: context.Background(),
+				Status:  status,
+				TTLSec:  86400,
+			}).Run()
+			if result.Err != nil {
+				t.Fatal(result.Err)
+			}
+			if result.StoryID != 100 {
+				t.Errorf("Expected story 100, got %d", result.StoryID)
+			}
+			if result.NewJobs != step.wantNew || result.UpdatedJobs != step.wantUpdated ||
+				result.WithdrawnJobs != step.wantWithdrawn {
+				t.Errorf(
+					"Expected %d new, %d updated and %d withdrawn, got %d, %d and %d",
+					step.wantNew, step.wantUpdated, step.wantWithdrawn,
+					result.NewJobs, result.UpdatedJobs, result.WithdrawnJobs,
+				)
+			}
+
+			rejects, err := db.GetAllRejectsByStoryId(100)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var rejectIDs []int
+			for _, r := range rejects {
+				rejectIDs = append(rejectIDs, r.Id)
+			}
+			if !slices.Equal(rejectIDs, step.wantRejects) {
+				t.Errorf("Expected rejects %v, got %v", step.wantRejects, rejectIDs)
+			}
+
+			jobs, err := db.GetAllJobsByStoryId(100, db.OrderNone)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var jobIDs []int
+			for _, job := range jobs {
+				if job.WithdrawnTime == 0 {
+					jobIDs = append(jobIDs, job.Id)
+				}
+			}
+			slices.Sort(jobIDs)
+			if !slices.Equal(jobIDs, step.wantJobs) {
+				t.Errorf("Expected jobs %v, got %v", step.wantJobs, jobIDs)
+			}
+		})
+	}
+}
+
+func TestFetchMaxRefresh(t *testing.T) {
+	setupAppTest(t, nil)
+
+	comment := func(id int, company string) map[string]any {
+		return map[string]any{
+			"by": "someone", "id": id, "parent": 100, "text": company + " | SRE | Remote", "time": 1727794900,
+			"type": "comment",
+		}
+	}
+	lines := []string{
+		cassetteLine(t, "/user/whoishiring/submitted.json", "[100]"),
+		cassetteItem(t, map[string]any{
+			"by":    "whoishiring",
+			"id":    100,
+			"kids":  []int{101, 102, 103},
+			"time":  1727794816,
+			"title": "Ask HN: Who is hiring? (October 2024)",
+			"type":  "story",
+		}),
+		cassetteLine(t, "/updates.json", mustJSON(t, map[string]any{"items": []int{}, "profiles": []string{}})),
+		cassetteItem(t, comment(101, "Acme Corp")),
+		cassetteItem(t, comment(102, "Widgets Inc")),
+		cassetteItem(t, comment(103, "Gadgets LLC")),
+	}
+	path := filepath.Join(t.TempDir(), "cassette.jsonl")
+	err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = hn.UseCassette(path, hn.CassetteReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fetch := func(maxRefresh int) *FetchResult {
+		status := make(chan FetchStatusUpdate)
+		go func() {
+			for range status {
+			}
+		}()
+		result := NewFetcher(FetchOptions{
+			Context:    context.Background(),
+			Status:     status,
+			TTLSec:     86400,
+			MaxRefresh: maxRefresh,
+		}).Run()
+		if result.Err != nil {
+			t.Fatal(result.Err)
+		}
+		return result
+	}
+	fetch(0)
+
+	// All three have gone stale, 102 the longest ago
+	now := time.Now().UTC().Unix()
+	staleSince := map[int]int64{101: now - 3*86400, 102: now - 4*86400, 103: now - 2*86400}
+	jobs, err := db.GetAllJobsByStoryId(100, db.OrderNone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, job := range jobs {
+		job.FetchedTime = staleSince[job.Id]
+		err = db.UpsertJob(job)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fetch(2)
+	jobs, err = db.GetAllJobsByStoryId(100, db.OrderNone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var refetched []int
+	for _, job := range jobs {
+		if job.FetchedTime != staleSince[job.Id] {
+			refetched = append(refetched, job.Id)
+		}
+	}
+	slices.Sort(refetched)
+	if want := []int{101, 102}; !slices.Equal(refetched, want) {
+		t.Errorf("Expected the oldest 2 stale jobs %v to be refetched, got %v", want, refetched)
+	}
+}
@@ -0,0 +1,28 @@
+package app
+
+import (
+	"fmt"
+	"github.com/mwinters0/hnjobs/config"
+	"github.com/mwinters0/hnjobs/hn"
+	"time"
+)
+
+// ConfigureNetwork applies the config file's network settings to the HN client.  Call it again after reloading the
+// config.
+func ConfigureNetwork() error {
+	nc := config.GetConfig().Network
+	err := hn.Configure(hn.ClientOptions{
+		Timeout:        time.Duration(nc.TimeoutSecs) * time.Second,
+		UserAgent:      nc.UserAgent,
+		ProxyURL:       nc.ProxyURL,
+		MaxIdleConns:   nc.MaxIdleConns,
+		BaseURL:        nc.BaseURL,
+		AlgoliaBaseURL: nc.AlgoliaBaseURL,
+		RatePerSec:     nc.RatePerSec,
+		Burst:          nc.Burst,
+	})
+	if err != nil {
+		return fmt.Errorf("error in network config: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mwinters0/hnjobs/db"
+	"github.com/mwinters0/hnjobs/hn"
+	"github.com/mwinters0/hnjobs/scoring"
+	"strings"
+	"time"
+)
+
+// PromoteReject turns a rejected comment into a job, for when it really was a job and we just couldn't parse it.
+// Later fetches keep the company name you gave it.
+func PromoteReject(id int, companyName string) (*db.Job, error) {
+	companyName = strings.TrimSpace(companyName)
+	if companyName == "" {
+		return nil, errors.New("company name is required")
+	}
+	companyName = truncateCompanyName(companyName, maxCompanyNameLength)
+	r, err := db.GetRejectById(id)
+	if errors.Is(err, db.ErrNoResults) {
+		return nil, fmt.Errorf("no rejected comment with id %d", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error finding rejected comment: %v", err)
+	}
+	c := &hn.Comment{
+		Id:            r.Id,
+		Parent:        r.Parent,
+		Text:          r.Text,
+		Time:          r.Time,
+		GoTime:        r.GoTime,
+		FetchedTime:   r.FetchedTime,
+		FetchedGoTime: time.Unix(r.FetchedTime, 0),
+	}
+	job, err := newJobFromHNComment(c, companyName)
+	if err != nil {
+		return nil, err
+	}
+	annotateJob(job)
+	scoring.ScoreDBComment(job)
+	err = db.UpsertJob(job) // also deletes the reject
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
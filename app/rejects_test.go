@@ -0,0 +1,54 @@
+package app
+
+import (
+	"errors"
+	"github.com/mwinters0/hnjobs/db"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestPromoteReject(t *testing.T) {
+	setupAppTest(t, nil)
+	err := db.UpsertReject(&db.Reject{
+		Id:     103,
+		Parent: 100,
+		Text:   "We're hiring SREs in Zürich, remote OK. Email jobs@example.com",
+		Time:   1727794900,
+		Reason: "no delimiters",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = PromoteReject(103, "  ")
+	if err == nil {
+		t.Error("Expected an error for an empty company name")
+	}
+	_, err = PromoteReject(999, "Acme")
+	if err == nil {
+		t.Error("Expected an error for a comment which wasn't rejected")
+	}
+
+	// 29 bytes of "a" and then "é", which is 2 bytes, straddles the limit
+	job, err := PromoteReject(103, "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaé Zürich")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !utf8.ValidString(job.Company) || len(job.Company) > maxCompanyNameLength {
+		t.Errorf("Expected a valid company name of at most %d bytes, got %q", maxCompanyNameLength, job.Company)
+	}
+	if job.Company != "aaaaaaaaaaaaaaaaaaaaaaaaaaaaa" {
+		t.Errorf("Expected the name to be cut before the é, got %q", job.Company)
+	}
+	dbJob, err := db.GetJobById(103)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dbJob.Company != job.Company {
+		t.Errorf("Expected company %q in the DB, got %q", job.Company, dbJob.Company)
+	}
+	_, err = db.GetRejectById(103)
+	if !errors.Is(err, db.ErrNoResults) {
+		t.Errorf("Expected the reject to be gone, got %v", err)
+	}
+}
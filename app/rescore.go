@@ -17,6 +17,8 @@ func ReScore(storyID int) (int, error) {
 
 	numRescored := 0
 	for _, dbc := range dbcs {
+		// also re-parse, in case the parsers have improved since we fetched
+		annotateJob(dbc)
 		scoring.ScoreDBComment(dbc)
 		err = db.UpsertJob(dbc)
 		if err != nil {
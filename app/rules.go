@@ -0,0 +1,57 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mwinters0/hnjobs/db"
+	"github.com/mwinters0/hnjobs/jobheader"
+	"github.com/mwinters0/hnjobs/scoring"
+)
+
+// JobFromText makes a job out of text which isn't in the DB, e.g. a posting you're writing rules for.  text can be
+// HTML like HN's or plain text.
+func JobFromText(text string) *db.Job {
+	job := &db.Job{Text: text}
+	job.Company = jobheader.Parse(text).Company
+	annotateJob(job)
+	return job
+}
+
+// JobFromDB loads a job and re-parses it like ReScore does, so that rules see what they would see after a rescore
+func JobFromDB(id int) (*db.Job, error) {
+	job, err := db.GetJobById(id)
+	if errors.Is(err, db.ErrNoResults) {
+		return nil, fmt.Errorf("no job with id %d", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error finding job: %v", err)
+	}
+	annotateJob(job)
+	return job, nil
+}
+
+// RuleHitCounts counts how many jobs in the whole DB each of the current rules matches, in the same order as
+// scoring.GetRules()
+func RuleHitCounts() (counts []int, numJobs int, err error) {
+	stories, err := db.GetAllStories()
+	if err != nil && !errors.Is(err, db.ErrNoResults) {
+		return nil, 0, fmt.Errorf("error finding stories in the database: %v", err)
+	}
+	counts = make([]int, len(scoring.GetRules()))
+	for _, story := range stories {
+		jobs, err := db.GetAllJobsByStoryId(story.Id, db.OrderNone)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error finding jobs in the database: %v", err)
+		}
+		for _, job := range jobs {
+			annotateJob(job)
+			for i, result := range scoring.Explain(job) {
+				if result.Matched {
+					counts[i]++
+				}
+			}
+			numJobs++
+		}
+	}
+	return counts, numJobs, nil
+}
@@ -0,0 +1,86 @@
+package app
+
+import (
+	"github.com/mwinters0/hnjobs/config"
+	"github.com/mwinters0/hnjobs/db"
+	"github.com/mwinters0/hnjobs/hn"
+	"github.com/mwinters0/hnjobs/scoring"
+	"slices"
+	"testing"
+)
+
+func TestRules(t *testing.T) {
+	setupAppTest(t, []config.ScoringRule{
+		{TextFound: "(?i)sre", Score: 2},
+		{Tech: "go", Score: 3},
+		{TextFound: "python", Score: -5},
+		{Numeric: "salary_min >= 100k", Score: 4},
+		{All: []config.ScoringRule{{WorkMode: "remote"}, {Tech: "kubernetes"}}, Score: 1},
+	})
+	job := JobFromText("Acme | SRE | Remote<p>We use golang and k8s.")
+
+	// matched, known, and the matched text by rule
+	want := []struct {
+		matched bool
+		known   bool
+		spans   []string
+	}{
+		{true, true, []string{"SRE"}},
+		{true, true, []string{"golang"}},
+		{false, true, nil},
+		{false, false, nil}, // no salary
+		{true, true, []string{"k8s"}},
+	}
+	plainText := "Acme | SRE | Remote\n\nWe use golang and k8s."
+	results := scoring.Explain(job)
+	if len(results) != len(want) {
+		t.Fatalf("expected %d results, got %d", len(want), len(results))
+	}
+	for i, result := range results {
+		var spans []string
+		for _, span := range result.Spans {
+			spans = append(spans, plainText[span[0]:span[1]])
+		}
+		if result.Matched != want[i].matched || result.Known != want[i].known || !slices.Equal(spans, want[i].spans) {
+			t.Errorf(
+				"rule %d: expected matched=%v known=%v %q, got matched=%v known=%v %q",
+				i+1, want[i].matched, want[i].known, want[i].spans, result.Matched, result.Known, spans,
+			)
+		}
+	}
+	if score := scoring.ScoreDBComment(job); score != 6 {
+		t.Errorf("expected score 6, got %d", score)
+	}
+	var points []int
+	for _, hit := range job.RuleHits {
+		points = append(points, hit.Points)
+	}
+	if !slices.Equal(points, []int{2, 3, 1}) {
+		t.Errorf("expected points [2 3 1], got %v", points)
+	}
+
+	// and the same job from the DB
+	job.Id, job.Parent = 101, 100
+	err := db.UpsertJob(job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.UpsertStory(&hn.Story{Id: 100, Title: "Ask HN: Who is hiring? (October 2024)"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromDB, err := JobFromDB(101)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if score := scoring.ScoreDBComment(fromDB); score != 6 {
+		t.Errorf("expected score 6 from the DB, got %d", score)
+	}
+	counts, numJobs, err := RuleHitCounts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if numJobs != 1 || !slices.Equal(counts, []int{1, 1, 0, 0, 1}) {
+		t.Errorf("expected counts [1 1 0 0 1] across 1 job, got %v across %d", counts, numJobs)
+	}
+}
@@ -0,0 +1,192 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/mwinters0/hnjobs/hn"
+	"iter"
+	"slices"
+	"strings"
+)
+
+// Source is a kind of HN thread whose top-level comments are job postings (or close enough.)
+type Source interface {
+	// Name is stored with each story so we know where it came from.
+	Name() string
+	// DiscoverStories yields the source's stories, newest first.  Stop iterating once you've seen enough.
+	DiscoverStories(ctx context.Context) iter.Seq2[*hn.Story, error]
+	// FetchStory fetches one of the source's stories by ID, with the IDs of its items in Kids.  It fails with
+	// ErrNotInSource if the story belongs to some other source.
+	FetchStory(ctx context.Context, id int) (*hn.Story, error)
+	// ListItemIDs returns the IDs of the items (top-level comments) in the story.
+	ListItemIDs(ctx context.Context, story *hn.Story) ([]int, error)
+	// FetchItem fetches a single item.
+	FetchItem(ctx context.Context, id int) (*hn.Comment, error)
+}
+
+const (
+	SourceNameWhoIsHiring = "whoishiring"
+	SourceNameFreelancer  = "freelancer"
+	SourceNameStoryList   = "storylist"
+)
+
+var ErrNotInSource = errors.New("story isn't from this source")
+
+const WhoIsHiringString string = "Who is hiring?"
+const FreelancerString string = "Freelancer? Seeking freelancer?"
+
+// NewSource returns the named built-in Source.  storyIDs is only for the storylist source.
+func NewSource(name string, storyIDs []int, backend hn.Backend) (Source, error) {
+	if len(storyIDs) > 0 && name != SourceNameStoryList {
+		return nil, fmt.Errorf("story IDs only work with the %q source", SourceNameStoryList)
+	}
+	switch name {
+	case "", SourceNameWhoIsHiring:
+		return NewWhoIsHiringSource(backend), nil
+	case SourceNameFreelancer:
+		return NewFreelancerSource(backend), nil
+	case SourceNameStoryList:
+		if len(storyIDs) == 0 {
+			return nil, fmt.Errorf("the %q source needs some story IDs", SourceNameStoryList)
+		}
+		return NewStoryListSource(storyIDs, backend), nil
+	default:
+		return nil, fmt.Errorf("unknown source %q", name)
+	}
+}
+
+// itemSource handles listing / fetching items via an hn.Backend, which is the same for all of our sources.
+type itemSource struct {
+	backend hn.Backend
+}
+
+func (is *itemSource) ListItemIDs(ctx context.Context, story *hn.Story) ([]int, error) {
+	// Ask the backend for the story even though we probably already have it, because some backends (algolia) fetch
+	// all the comments at the same time.
+	s, err := is.backend.FetchStory(ctx, story.Id)
+	if err != nil {
+		return nil, err
+	}
+	return s.Kids, nil
+}
+
+func (is *itemSource) FetchItem(ctx context.Context, id int) (*hn.Comment, error) {
+	return is.backend.FetchComment(ctx, id)
+}
+
+// SubmissionsSource finds stories submitted by a user whose titles contain a string, e.g. the monthly "Who is
+// hiring?" stories submitted by the whoishiring account.
+type SubmissionsSource struct {
+	itemSource
+	name          string
+	user          string
+	titleContains string
+}
+
+func NewSubmissionsSource(name string, user string, titleContains string, backend hn.Backend) *SubmissionsSource {
+	if backend == nil {
+		backend = &hn.FirebaseBackend{}
+	}
+	return &SubmissionsSource{
+		itemSource:    itemSource{backend},
+		name:          name,
+		user:          user,
+		titleContains: titleContains,
+	}
+}
+
+func NewWhoIsHiringSource(backend hn.Backend) *SubmissionsSource {
+	return NewSubmissionsSource(SourceNameWhoIsHiring, "whoishiring", WhoIsHiringString, backend)
+}
+
+func NewFreelancerSource(backend hn.Backend) *SubmissionsSource {
+	return NewSubmissionsSource(SourceNameFreelancer, "whoishiring", FreelancerString, backend)
+}
+
+func (ss *SubmissionsSource) Name() string {
+	return ss.name
+}
+
+func (ss *SubmissionsSource) FetchStory(ctx context.Context, id int) (*hn.Story, error) {
+	s, err := ss.backend.FetchStory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.Contains(s.Title, ss.titleContains) {
+		return nil, fmt.Errorf("%w: %d isn't a %s story", ErrNotInSource, id, ss.name)
+	}
+	return s, nil
+}
+
+func (ss *SubmissionsSource) DiscoverStories(ctx context.Context) iter.Seq2[*hn.Story, error] {
+	return func(yield func(*hn.Story, error) bool) {
+		submissions, err := hn.FetchSubmissions(ctx, ss.user)
+		if err != nil {
+			yield(nil, fmt.Errorf("error fetching %s's submissions: %w", ss.user, err))
+			return
+		}
+		for _, id := range submissions {
+			// always use firebase here, because algolia would fetch every comment of every story
+			s, err := hn.FetchStory(ctx, id)
+			if errors.Is(err, hn.ErrWrongItemType) || errors.Is(err, hn.ErrItemNotFound) {
+				// users also submit comments, polls, etc.
+				continue
+			}
+			if err != nil {
+				yield(nil, fmt.Errorf("failed to retrieve story %d from API: %w", id, err))
+				return
+			}
+			if !strings.Contains(s.Title, ss.titleContains) {
+				continue
+			}
+			if !yield(s, nil) {
+				return
+			}
+		}
+	}
+}
+
+// StoryListSource is a fixed list of story IDs, e.g. for one-off threads.
+type StoryListSource struct {
+	itemSource
+	ids []int
+}
+
+func NewStoryListSource(ids []int, backend hn.Backend) *StoryListSource {
+	if backend == nil {
+		backend = &hn.FirebaseBackend{}
+	}
+	ids = slices.Clone(ids)
+	slices.Sort(ids)
+	slices.Reverse(ids) // IDs increase over time, so this is newest first
+	return &StoryListSource{
+		itemSource: itemSource{backend},
+		ids:        ids,
+	}
+}
+
+func (sls *StoryListSource) Name() string {
+	return SourceNameStoryList
+}
+
+func (sls *StoryListSource) FetchStory(ctx context.Context, id int) (*hn.Story, error) {
+	if !slices.Contains(sls.ids, id) {
+		return nil, fmt.Errorf("%w: %d isn't in the story list", ErrNotInSource, id)
+	}
+	return sls.backend.FetchStory(ctx, id)
+}
+
+func (sls *StoryListSource) DiscoverStories(ctx context.Context) iter.Seq2[*hn.Story, error] {
+	return func(yield func(*hn.Story, error) bool) {
+		for _, id := range sls.ids {
+			s, err := hn.FetchStory(ctx, id)
+			if err != nil {
+				err = fmt.Errorf("failed to retrieve story %d from API: %w", id, err)
+			}
+			if !yield(s, err) || err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"github.com/mwinters0/hnjobs/db"
+	"github.com/mwinters0/hnjobs/hn"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewSource(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		storyIDs []int
+		want     string // "" for an error
+	}{
+		{"default", "", nil, SourceNameWhoIsHiring},
+		{"freelancer", SourceNameFreelancer, nil, SourceNameFreelancer},
+		{"story list", SourceNameStoryList, []int{200}, SourceNameStoryList},
+		{"story list without IDs", SourceNameStoryList, nil, ""},
+		{"IDs for another source", SourceNameWhoIsHiring, []int{200}, ""},
+		{"unknown", "nope", nil, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			source, err := NewSource(test.source, test.storyIDs, nil)
+			if test.want == "" {
+				if err == nil {
+					t.Errorf("Expected an error, got source %q", source.Name())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if source.Name() != test.want {
+				t.Errorf("Expected source %q, got %q", test.want, source.Name())
+			}
+		})
+	}
+}
+
+func TestFetchStoryList(t *testing.T) {
+	setupAppTest(t, nil)
+
+	comment := func(id int, text string) map[string]any {
+		return map[string]any{"by": "someone", "id": id, "parent": 200, "text": text, "time": 1727794900, "type": "comment"}
+	}
+	lines := []string{
+		cassetteItem(t, map[string]any{
+			"by":    "someone",
+			"id":    200,
+			"kids":  []int{201, 202},
+			"time":  1727794816,
+			"title": "Ask HN: Who's hiring in Europe?",
+			"type":  "story",
+		}),
+		cassetteLine(t, "/updates.json", mustJSON(t, map[string]any{"items": []int{}, "profiles": []string{}})),
+		cassetteItem(t, comment(201, "Acme Corp | SRE | Remote | $150k-$200k<p>We use golang and rust.")),
+		cassetteItem(t, comment(202, "Widgets Inc | Backend | Onsite Berlin<p>Python shop")),
+	}
+	path := filepath.Join(t.TempDir(), "cassette.jsonl")
+	err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = hn.UseCassette(path, hn.CassetteReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source, err := NewSource(SourceNameStoryList, []int{200}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fetch := func(storyID int) *FetchResult {
+		status := make(chan FetchStatusUpdate)
+		go func() {
+			for range status {
+			}
+		}()
+		return NewFetcher(FetchOptions{
+			Context: context.Background(),
+			Status:  status,
+			StoryID: storyID,
+			TTLSec:  86400,
+			Source:  source,
+		}).Run()
+	}
+
+	result := fetch(0)
+	if result.Err != nil {
+		t.Fatal(result.Err)
+	}
+	if result.StoryID != 200 || result.NewJobs != 2 {
+		t.Errorf("Expected 2 new jobs in story 200, got %d in story %d", result.NewJobs, result.StoryID)
+	}
+	latest, err := db.GetLatestStory(SourceNameStoryList)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.Id != 200 || latest.Source != SourceNameStoryList {
+		t.Errorf("Expected story 200 from %q, got %d from %q", SourceNameStoryList, latest.Id, latest.Source)
+	}
+
+	result = fetch(100)
+	if !errors.Is(result.Err, ErrNotInSource) {
+		t.Errorf("Expected ErrNotInSource for a story which isn't in the list, got %v", result.Err)
+	}
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/mwinters0/hnjobs/app"
+	"github.com/mwinters0/hnjobs/config"
+	"github.com/mwinters0/hnjobs/hn"
+	"github.com/spf13/cobra"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+)
+
+var backfillCmd = &cobra.Command{
+	Use:   "backfill",
+	Short: "Fetch past months' job stories",
+	Long: `Fetch and score all of the job stories posted in the last N months.
+
+Stories which were already completely fetched are skipped, so if a backfill is interrupted you can just run it again
+to pick up where it left off.`,
+	Run: backfill,
+}
+
+var flagBackfillMonths int
+var flagBackfillSource string
+var flagBackfillQuiet bool
+var flagBackfillStoryIDs []int
+
+func init() {
+	rootCmd.AddCommand(backfillCmd)
+	backfillCmd.Flags().IntVarP(
+		&flagBackfillMonths,
+		"months", "m",
+		12,
+		"How many months back to fetch",
+	)
+	backfillCmd.Flags().BoolVarP(
+		&flagBackfillQuiet,
+		"quiet", "q",
+		false,
+		"Don't print status info. Might still print errors.",
+	)
+	backfillCmd.Flags().StringVar(
+		&flagBackfillSource,
+		"source",
+		app.SourceNameWhoIsHiring,
+		sourceFlagUsage,
+	)
+	backfillCmd.Flags().IntSliceVar(
+		&flagBackfillStoryIDs,
+		"story-ids",
+		nil,
+		storyIDsFlagUsage,
+	)
+}
+
+func backfill(cmd *cobra.Command, args []string) {
+	if flagBackfillMonths < 1 {
+		log.Fatal("--months must be at least 1")
+	}
+	backend, err := hn.NewBackend(config.GetConfig().Fetch.Backend)
+	if err != nil {
+		log.Fatal(err)
+	}
+	source, err := sourceFromFlags(cmd, flagBackfillSource, flagBackfillStoryIDs, backend)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Ctrl-C stops cleanly so that we can resume later
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	status := make(chan app.FetchStatusUpdate)
+	bo := app.BackfillOptions{
+		Context: ctx,
+		Status:  status,
+		Since:   time.Now().AddDate(0, -flagBackfillMonths, 0),
+		TTLSec:  config.GetConfig().Cache.TTLSecs,
+		Source:  source,
+	}
+	go app.BackfillAsync(bo)
+	for fsu := range status {
+		if !flagBackfillQuiet {
+			fmt.Println(fsu.Message)
+		}
+		switch fsu.UpdateType {
+		case app.UpdateTypeFatal:
+			if !flagBackfillQuiet {
+				fmt.Println("Backfill experienced fatal errors.")
+			}
+			os.Exit(1)
+		case app.UpdateTypeDone:
+			return
+		}
+	}
+	log.Fatal("BUG: cmd/backfill: status channel closed before UpdateTypeDone!")
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/mwinters0/hnjobs/app"
 	"github.com/mwinters0/hnjobs/db"
 	"github.com/mwinters0/hnjobs/hn"
 	"github.com/spf13/cobra"
@@ -16,20 +17,41 @@ var dumpCmd = &cobra.Command{
 	Run:   dump,
 }
 
+var flagDumpRevisions bool
+var flagDumpSource string
+
 func init() {
 	rootCmd.AddCommand(dumpCmd)
+	dumpCmd.Flags().BoolVarP(
+		&flagDumpRevisions,
+		"revisions", "r",
+		false,
+		"Include every version of each job's text, oldest first",
+	)
+	dumpCmd.Flags().StringVar(
+		&flagDumpSource,
+		"source",
+		app.SourceNameWhoIsHiring,
+		"Latest story of this kind: \""+app.SourceNameWhoIsHiring+"\", \""+app.SourceNameFreelancer+"\" or \""+
+			app.SourceNameStoryList+"\"",
+	)
 }
 
 type dumpData struct {
 	Story *hn.Story
-	Jobs  []*db.Job
+	Jobs  []*dumpJob
+}
+
+type dumpJob struct {
+	*db.Job
+	Withdrawn bool           // the poster deleted it, see WithdrawnTime
+	Revisions []*db.Revision `json:",omitempty"` // with --revisions
 }
 
 func dump(cmd *cobra.Command, args []string) {
-	latest, err := db.GetLatestStory()
+	latest, err := db.GetLatestStory(flagDumpSource)
 	if errors.Is(err, db.ErrNoResults) {
 		panic("No stories found")
-		return
 	}
 	if err != nil {
 		panic(fmt.Errorf("error finding latest job story from DB: %v", err))
@@ -42,9 +64,22 @@ func dump(cmd *cobra.Command, args []string) {
 		panic(fmt.Sprintf("No jobs in DB for latest story ID %d (%s)", latest.Id, latest.Title))
 	}
 
+	revisions := make(map[int][]*db.Revision)
+	if flagDumpRevisions {
+		revs, err := db.GetRevisionsByStoryId(latest.Id)
+		if err != nil {
+			panic(fmt.Sprintf("error getting job revisions from DB: %v", err))
+		}
+		for _, r := range revs {
+			revisions[r.JobId] = append(revisions[r.JobId], r)
+		}
+	}
+
 	d := &dumpData{
 		Story: latest,
-		Jobs:  jobs,
+	}
+	for _, job := range jobs {
+		d.Jobs = append(d.Jobs, &dumpJob{Job: job, Withdrawn: job.WithdrawnTime != 0, Revisions: revisions[job.Id]})
 	}
 	j, err := json.Marshal(d)
 	if err != nil {
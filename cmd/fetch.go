@@ -2,12 +2,15 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/mwinters0/hnjobs/app"
 	"github.com/mwinters0/hnjobs/config"
+	"github.com/mwinters0/hnjobs/hn"
 	"github.com/spf13/cobra"
 	"log"
 	"os"
+	"time"
 )
 
 // fetchCmd represents the fetch command
@@ -19,9 +22,15 @@ var fetchCmd = &cobra.Command{
 }
 
 var flagForce bool
+var flagIncremental bool
 var flagQuiet bool
 var flagStoryID int
 var flagExit bool
+var flagSource string
+var flagStoryIDs []int
+var flagMaxRefresh int
+var flagStats bool
+var flagFetchJSON bool
 
 func init() {
 	rootCmd.AddCommand(fetchCmd)
@@ -31,6 +40,12 @@ func init() {
 		false,
 		"Fetch and score all comments, ignoring cache TTL",
 	)
+	fetchCmd.Flags().BoolVarP(
+		&flagIncremental,
+		"incremental", "i",
+		false,
+		"Only fetch new jobs and jobs which HN says changed in the last few minutes, ignoring cache TTL",
+	)
 	fetchCmd.Flags().BoolVarP(
 		&flagQuiet,
 		"quiet", "q",
@@ -49,19 +64,67 @@ func init() {
 		false,
 		"Exit code is 0 only if new jobs were fetched. Empty success is code 42.",
 	)
+	fetchCmd.Flags().IntVar(
+		&flagMaxRefresh,
+		"max-refresh",
+		0,
+		"Refetch at most this many TTL-expired jobs (oldest first). 0 means no limit.",
+	)
+	fetchCmd.Flags().StringVar(
+		&flagSource,
+		"source",
+		app.SourceNameWhoIsHiring,
+		sourceFlagUsage,
+	)
+	fetchCmd.Flags().IntSliceVar(
+		&flagStoryIDs,
+		"story-ids",
+		nil,
+		storyIDsFlagUsage,
+	)
+	fetchCmd.Flags().BoolVar(
+		&flagStats,
+		"stats",
+		false,
+		"Print where the time went (HTTP, scoring, DB) when done, for tuning the fetch settings",
+	)
+	fetchCmd.Flags().BoolVar(
+		&flagFetchJSON,
+		"json",
+		false,
+		"Print one JSON object per status update and a summary object at the end, instead of text",
+	)
 }
 
 func fetch(cmd *cobra.Command, args []string) {
+	backend, err := hn.NewBackend(config.GetConfig().Fetch.Backend)
+	if err != nil {
+		log.Fatal(err)
+	}
+	source, err := sourceFromFlags(cmd, flagSource, flagStoryIDs, backend)
+	if err != nil {
+		log.Fatal(err)
+	}
 	status := make(chan app.FetchStatusUpdate)
 	fo := app.FetchOptions{
-		Context:     context.Background(),
-		Status:      status,
-		ModeForce:   flagForce,
-		StoryID:     flagStoryID,
-		TTLSec:      config.GetConfig().Cache.TTLSecs,
-		MustContain: app.WhoIsHiringString,
+		Context:         context.Background(),
+		Status:          status,
+		ModeForce:       flagForce,
+		ModeIncremental: flagIncremental,
+		StoryID:         flagStoryID,
+		TTLSec:          config.GetConfig().Cache.TTLSecs,
+		MaxRefresh:      flagMaxRefresh,
+		Source:          source,
+	}
+	fetcher := app.NewFetcher(fo)
+	results := make(chan *app.FetchResult, 1)
+	go func() {
+		results <- fetcher.Run()
+	}()
+	if flagFetchJSON {
+		fetchJSON(status, results)
+		return
 	}
-	go app.FetchAsync(fo)
 	for {
 		select {
 		case fsu, ok := <-status:
@@ -77,14 +140,21 @@ func fetch(cmd *cobra.Command, args []string) {
 				if !flagQuiet {
 					fmt.Println("Fetch experienced fatal errors.")
 				}
+				if flagStats {
+					printFetchStats(fetcher, <-results)
+				}
 				os.Exit(1)
 			case app.UpdateTypeGeneric,
 				app.UpdateTypeNewStory,
 				app.UpdateTypeNonFatalErr,
 				app.UpdateTypeBadComment,
-				app.UpdateTypeJobFetched:
+				app.UpdateTypeJobFetched,
+				app.UpdateTypeJobWithdrawn:
 			case app.UpdateTypeDone:
 				// This is where we intend to exit
+				if flagStats {
+					printFetchStats(fetcher, <-results)
+				}
 				if flagExit && fsu.Value == 0 {
 					// no new jobs fetched
 					os.Exit(42)
@@ -96,3 +166,141 @@ func fetch(cmd *cobra.Command, args []string) {
 		}
 	}
 }
+
+func printFetchStats(f *app.Fetcher, fr *app.FetchResult) {
+	t := fr.Timings
+	perComment := func(d time.Duration) string {
+		if fr.Comments == 0 {
+			return ""
+		}
+		return fmt.Sprintf(" (%v per comment)", (d / time.Duration(fr.Comments)).Round(time.Microsecond))
+	}
+	fmt.Printf("Fetched %d comments in %v with %d workers, %d processors, queue depth %d, batch size %d\n",
+		fr.Comments, t.Total.Round(time.Millisecond), f.Workers, f.Processors, f.QueueDepth, f.BatchSize,
+	)
+	fmt.Printf("  HTTP:    %v%s\n", t.HTTP.Round(time.Millisecond), perComment(t.HTTP))
+	fmt.Printf("  Scoring: %v%s\n", t.Scoring.Round(time.Millisecond), perComment(t.Scoring))
+	fmt.Printf("  DB:      %v in %d batches\n", t.DB.Round(time.Millisecond), t.DBBatches)
+	fmt.Println("(HTTP, scoring and DB times are summed across workers, so they can add up to more than the total)")
+}
+
+var updateTypeNames = map[app.UpdateType]string{
+	app.UpdateTypeGeneric:      "generic",
+	app.UpdateTypeNewStory:     "new_story",
+	app.UpdateTypeNonFatalErr:  "error",
+	app.UpdateTypeFatal:        "fatal",
+	app.UpdateTypeBadComment:   "bad_comment",
+	app.UpdateTypeJobFetched:   "job",
+	app.UpdateTypeJobWithdrawn: "withdrawn",
+	app.UpdateTypeDone:         "done",
+}
+
+// fetchJSONCounts are running totals, so a consumer can show progress without keeping its own
+type fetchJSONCounts struct {
+	Jobs        int `json:"jobs"`
+	Withdrawn   int `json:"withdrawn"`
+	BadComments int `json:"bad_comments"`
+	Errors      int `json:"errors"`
+}
+
+type fetchJSONUpdate struct {
+	Type    string          `json:"type"`
+	Message string          `json:"message"`
+	StoryID int             `json:"story_id,omitempty"`
+	JobID   int             `json:"job_id,omitempty"`
+	Score   *int            `json:"score,omitempty"`
+	Error   string          `json:"error,omitempty"`
+	Counts  fetchJSONCounts `json:"counts"`
+}
+
+type fetchJSONSummary struct {
+	Type            string `json:"type"` // always "summary"
+	StoryID         int    `json:"story_id"`
+	NewJobs         int    `json:"new_jobs"`
+	UpdatedJobs     int    `json:"updated_jobs"`
+	Comments        int    `json:"comments"`
+	WithdrawnJobs   int    `json:"withdrawn_jobs"`
+	SkippedComments []int  `json:"skipped_comments"`
+	Cancelled       bool   `json:"cancelled"`
+	Error           string `json:"error,omitempty"`
+	Timings         struct {
+		TotalMs   int64 `json:"total_ms"`
+		HTTPMs    int64 `json:"http_ms"`
+		ScoringMs int64 `json:"scoring_ms"`
+		DBMs      int64 `json:"db_ms"`
+		DBBatches int   `json:"db_batches"`
+	} `json:"timings"`
+}
+
+// fetchJSON is fetch's main loop for --json: one object per line, so that it can be piped into jq or read line by line
+func fetchJSON(status <-chan app.FetchStatusUpdate, results <-chan *app.FetchResult) {
+	enc := json.NewEncoder(os.Stdout)
+	var counts fetchJSONCounts
+	for fsu := range status {
+		u := fetchJSONUpdate{
+			Type:    updateTypeNames[fsu.UpdateType],
+			Message: fsu.Message,
+			JobID:   fsu.JobID,
+		}
+		if u.Type == "" {
+			log.Fatal(fmt.Sprintf("BUG: cmd/fetch: unhandled UpdateType %d", fsu.UpdateType))
+		}
+		if fsu.Error != nil {
+			u.Error = fsu.Error.Error()
+		}
+		switch fsu.UpdateType {
+		case app.UpdateTypeNewStory:
+			u.StoryID = fsu.Value
+		case app.UpdateTypeNonFatalErr:
+			counts.Errors++
+		case app.UpdateTypeBadComment:
+			counts.BadComments++
+		case app.UpdateTypeJobFetched:
+			counts.Jobs++
+			score := fsu.Value
+			u.Score = &score
+		case app.UpdateTypeJobWithdrawn:
+			counts.Withdrawn++
+		}
+		u.Counts = counts
+		if err := enc.Encode(u); err != nil {
+			log.Fatal(err)
+		}
+		if fsu.UpdateType != app.UpdateTypeFatal && fsu.UpdateType != app.UpdateTypeDone {
+			continue
+		}
+		fr := <-results
+		s := fetchJSONSummary{
+			Type:            "summary",
+			StoryID:         fr.StoryID,
+			NewJobs:         fr.NewJobs,
+			UpdatedJobs:     fr.UpdatedJobs,
+			Comments:        fr.Comments,
+			WithdrawnJobs:   fr.WithdrawnJobs,
+			SkippedComments: fr.SkippedComments,
+			Cancelled:       fr.Cancelled,
+		}
+		if s.SkippedComments == nil {
+			s.SkippedComments = []int{}
+		}
+		if fr.Err != nil {
+			s.Error = fr.Err.Error()
+		}
+		s.Timings.TotalMs = fr.Timings.Total.Milliseconds()
+		s.Timings.HTTPMs = fr.Timings.HTTP.Milliseconds()
+		s.Timings.ScoringMs = fr.Timings.Scoring.Milliseconds()
+		s.Timings.DBMs = fr.Timings.DB.Milliseconds()
+		s.Timings.DBBatches = fr.Timings.DBBatches
+		if err := enc.Encode(s); err != nil {
+			log.Fatal(err)
+		}
+		if fsu.UpdateType == app.UpdateTypeFatal {
+			os.Exit(1)
+		}
+		if flagExit && fsu.Value == 0 {
+			os.Exit(42)
+		}
+		return
+	}
+	log.Fatal("BUG: cmd/fetch: status channel closed before UpdateTypeDone!")
+}
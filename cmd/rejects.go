@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mwinters0/hnjobs/app"
+	"github.com/mwinters0/hnjobs/db"
+	"github.com/mwinters0/hnjobs/plaintext"
+	"github.com/spf13/cobra"
+	"log"
+	"strconv"
+	"strings"
+)
+
+var rejectsCmd = &cobra.Command{
+	Use:   "rejects",
+	Short: "List the comments which didn't look like jobs",
+	Long: `List the top-level comments from the current month's story which weren't stored as jobs, and why.
+
+Some of these are meta comments, but some are real jobs which just didn't have a parseable company name.  Use
+"hnjobs rejects promote" to turn one of those into a job.`,
+	Run: listRejects,
+}
+
+var rejectsPromoteCmd = &cobra.Command{
+	Use:   "promote <comment id> <company name>",
+	Short: "Turn a rejected comment into a job",
+	Long:  `Turn a rejected comment into a job with the given company name, and score it.`,
+	Args:  cobra.MinimumNArgs(2),
+	Run:   promoteReject,
+}
+
+var flagRejectsStoryID int
+var flagRejectsFull bool
+var flagRejectsSource string
+
+func init() {
+	rootCmd.AddCommand(rejectsCmd)
+	rejectsCmd.AddCommand(rejectsPromoteCmd)
+	rejectsCmd.Flags().IntVarP(
+		&flagRejectsStoryID,
+		"storyid", "s",
+		0,
+		"List rejects from this story instead of the latest one",
+	)
+	rejectsCmd.Flags().BoolVar(
+		&flagRejectsFull,
+		"full",
+		false,
+		"Print each comment's whole text instead of the first line",
+	)
+	rejectsCmd.Flags().StringVar(
+		&flagRejectsSource,
+		"source",
+		app.SourceNameWhoIsHiring,
+		"Latest story of this kind: \""+app.SourceNameWhoIsHiring+"\", \""+app.SourceNameFreelancer+"\" or \""+
+			app.SourceNameStoryList+"\"",
+	)
+}
+
+func listRejects(cmd *cobra.Command, args []string) {
+	storyID := flagRejectsStoryID
+	if storyID == 0 {
+		latest, err := db.GetLatestStory(flagRejectsSource)
+		if errors.Is(err, db.ErrNoResults) {
+			fmt.Println("No stories found")
+			return
+		}
+		if err != nil {
+			log.Fatal(fmt.Errorf("error finding latest job story from DB: %v", err))
+		}
+		storyID = latest.Id
+	}
+	rejects, err := db.GetAllRejectsByStoryId(storyID)
+	if err != nil {
+		log.Fatal(fmt.Errorf("error getting rejects from DB: %v", err))
+	}
+	if len(rejects) == 0 {
+		fmt.Printf("No rejected comments for story %d\n", storyID)
+		return
+	}
+	for _, r := range rejects {
+		text := plaintext.FromHTML(r.Text)
+		if !flagRejectsFull {
+			text, _, _ = strings.Cut(strings.TrimSpace(text), "\n")
+			if len(text) > 100 {
+				text = text[:97] + "..."
+			}
+		}
+		fmt.Printf("%d (%s) %s\n  %s\n", r.Id, r.GoTime.Format("2006-01-02"), r.Reason, text)
+	}
+}
+
+func promoteReject(cmd *cobra.Command, args []string) {
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		log.Fatalf("invalid comment id %q", args[0])
+	}
+	job, err := app.PromoteReject(id, strings.Join(args[1:], " "))
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Promoted %d to a job at %s [Score %d]\n", job.Id, job.Company, job.Score)
+}
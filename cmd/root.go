@@ -1,8 +1,11 @@
 package cmd
 
 import (
+	"errors"
 	"github.com/mwinters0/hnjobs/app"
+	"github.com/mwinters0/hnjobs/hn"
 	"github.com/spf13/cobra"
+	"log"
 	"os"
 )
 
@@ -14,7 +17,8 @@ var rootCmd = &cobra.Command{
 Just run the app without any commands / flags unless you think you're special.  Press F1 in the TUI for help.
 
 `,
-	Run: browse,
+	Run:               browse,
+	PersistentPreRunE: setupHN,
 	CompletionOptions: cobra.CompletionOptions{
 		DisableDefaultCmd: true,
 	},
@@ -27,11 +31,82 @@ func Execute() {
 	}
 }
 
+var flagRecord string
+var flagReplay string
+var flagBrowseSource string
+var flagBrowseStoryIDs []int
+
 func init() {
+	rootCmd.PersistentFlags().StringVar(
+		&flagRecord,
+		"record", "",
+		"Record every HN API response to this cassette file (or set $HNJOBS_RECORD)",
+	)
+	rootCmd.PersistentFlags().StringVar(
+		&flagReplay,
+		"replay", "",
+		"Replay HN API responses from this cassette file instead of using the network (or set $HNJOBS_REPLAY)",
+	)
+	rootCmd.Flags().StringVar(
+		&flagBrowseSource,
+		"source",
+		app.SourceNameWhoIsHiring,
+		sourceFlagUsage,
+	)
+	rootCmd.Flags().IntSliceVar(
+		&flagBrowseStoryIDs,
+		"story-ids",
+		nil,
+		storyIDsFlagUsage,
+	)
 	//rootCmd.Flags().BoolVarP(&app.BrowseOptions.MouseEnabled, "mouse", "m", true, "Set TTY mouse enabled (default --mouse=true)")
 }
 
+// setupHN configures the HN API client from the config file and flags
+func setupHN(cmd *cobra.Command, args []string) error {
+	err := app.ConfigureNetwork()
+	if err != nil {
+		return err
+	}
+
+	record := flagRecord
+	if record == "" {
+		record = os.Getenv("HNJOBS_RECORD")
+	}
+	replay := flagReplay
+	if replay == "" {
+		replay = os.Getenv("HNJOBS_REPLAY")
+	}
+	switch {
+	case record != "" && replay != "":
+		return errors.New("can't record and replay at the same time")
+	case record != "":
+		return hn.UseCassette(record, hn.CassetteRecord)
+	case replay != "":
+		return hn.UseCassette(replay, hn.CassetteReplay)
+	}
+	return nil
+}
+
+const sourceFlagUsage = "Which job threads to use: \"" + app.SourceNameWhoIsHiring + "\", \"" +
+	app.SourceNameFreelancer + "\" or \"" + app.SourceNameStoryList + "\" (with --story-ids)"
+const storyIDsFlagUsage = "Comma-separated story IDs for the \"" + app.SourceNameStoryList +
+	"\" source, e.g. one-off hiring threads. Implies --source=" + app.SourceNameStoryList
+
+// sourceFromFlags builds the Source picked by --source and --story-ids.  Giving story IDs without a --source means
+// the storylist source.
+func sourceFromFlags(cmd *cobra.Command, name string, storyIDs []int, backend hn.Backend) (app.Source, error) {
+	if len(storyIDs) > 0 && !cmd.Flags().Changed("source") {
+		name = app.SourceNameStoryList
+	}
+	return app.NewSource(name, storyIDs, backend)
+}
+
 func browse(cmd *cobra.Command, args []string) {
-	app.Browse()
+	source, err := sourceFromFlags(cmd, flagBrowseSource, flagBrowseStoryIDs, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	app.Browse(source.Name(), flagBrowseStoryIDs)
 	return
 }
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/mwinters0/hnjobs/app"
+	"github.com/mwinters0/hnjobs/db"
+	"github.com/mwinters0/hnjobs/plaintext"
+	"github.com/mwinters0/hnjobs/scoring"
+	"github.com/spf13/cobra"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var rulesCmd = &cobra.Command{
+	Use:   "rules",
+	Short: "Debug your scoring rules",
+}
+
+var rulesTestCmd = &cobra.Command{
+	Use:   "test [job id | file | -]",
+	Short: "Show how each scoring rule does against a job",
+	Long: `Show whether each scoring rule matches a job, what it matched, its points and the final score.
+
+The job can be the id of a job in the database, a file containing a posting (HTML like HN's, or plain text) or "-"
+to read one from stdin.  With no job, reads stdin unless --all is given.
+
+With --all, also count how many jobs in the whole database each rule matches.`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  testRules,
+}
+
+var flagRulesAll bool
+
+func init() {
+	rootCmd.AddCommand(rulesCmd)
+	rulesCmd.AddCommand(rulesTestCmd)
+	rulesTestCmd.Flags().BoolVar(
+		&flagRulesAll,
+		"all",
+		false,
+		"Count each rule's matches across every job in the database",
+	)
+}
+
+func testRules(cmd *cobra.Command, args []string) {
+	arg := ""
+	if len(args) > 0 {
+		arg = args[0]
+	}
+	if arg != "" || !flagRulesAll {
+		job, err := loadRulesTestJob(arg)
+		if err != nil {
+			log.Fatal(err)
+		}
+		printRuleResults(job)
+	}
+	if flagRulesAll {
+		counts, numJobs, err := app.RuleHitCounts()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if arg != "" {
+			fmt.Println()
+		}
+		fmt.Printf("Matches across all %d jobs in the database:\n", numJobs)
+		for i, r := range scoring.GetRules() {
+			fmt.Printf("%6d  rule %d: %s\n", counts[i], i+1, r.JSON)
+		}
+	}
+}
+
+// loadRulesTestJob gets the job from the database if arg is a number, otherwise from the file or stdin
+func loadRulesTestJob(arg string) (*db.Job, error) {
+	if id, err := strconv.Atoi(arg); err == nil {
+		return app.JobFromDB(id)
+	}
+	var text []byte
+	var err error
+	if arg == "" || arg == "-" {
+		text, err = io.ReadAll(os.Stdin)
+	} else {
+		text, err = os.ReadFile(arg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading job: %v", err)
+	}
+	return app.JobFromText(strings.TrimSpace(string(text))), nil
+}
+
+func printRuleResults(job *db.Job) {
+	plain := plaintext.FromHTML(job.Text)
+	for i, result := range scoring.Explain(job) {
+		r := result.Rule
+		fmt.Printf("rule %d: %s\n", i+1, r.JSON)
+		switch {
+		case result.Matched:
+			fmt.Printf("  matched, %+d\n", r.Score)
+		case !result.Known:
+			fmt.Println("  unknown (the job doesn't say), 0")
+		default:
+			fmt.Println("  didn't match, 0")
+		}
+		for _, span := range result.Spans {
+			fmt.Printf("    %s\n", inContext(plain, span[0], span[1]))
+		}
+	}
+	fmt.Printf("Final score: %d\n", scoring.ScoreDBComment(job))
+}
+
+// inContext shows plain[start:end] with a bit of the text around it, on one line
+func inContext(plain string, start, end int) string {
+	const around = 30
+	from := max(start-around, 0)
+	for from > 0 && !utf8.RuneStart(plain[from]) {
+		from--
+	}
+	to := min(end+around, len(plain))
+	for to < len(plain) && !utf8.RuneStart(plain[to]) {
+		to++
+	}
+	s := plain[from:start] + "[" + plain[start:end] + "]" + plain[end:to]
+	if from > 0 {
+		s = "..." + s
+	}
+	if to < len(plain) {
+		s += "..."
+	}
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -5,9 +5,18 @@ import (
 	"errors"
 	"fmt"
 	"github.com/adrg/xdg"
+	"github.com/mwinters0/hnjobs/gazetteer"
+	"github.com/mwinters0/hnjobs/jobheader"
 	"github.com/mwinters0/hnjobs/sanitview"
+	"github.com/mwinters0/hnjobs/techstack"
+	"github.com/mwinters0/hnjobs/workmode"
 	"os"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
+	_ "time/tzdata" // so that profile timezones work everywhere
 )
 
 var config ConfigObj
@@ -16,6 +25,9 @@ var configLoaded bool
 type ConfigObj struct {
 	Version int
 	Cache   CacheConfig   `json:"cache"`
+	Fetch   FetchConfig   `json:"fetch"`
+	Network NetworkConfig `json:"network"`
+	Profile ProfileConfig `json:"profile"`
 	Scoring ScoringConfig `json:"scoring"`
 	Display DisplayConfig `json:"display"`
 }
@@ -24,6 +36,66 @@ type CacheConfig struct {
 	TTLSecs int64 `json:"ttl_secs"`
 }
 
+// FetchConfig's tuning settings default to something sensible if zero
+type FetchConfig struct {
+	Backend    string `json:"backend"`               // "firebase" (default) or "algolia"
+	Workers    int    `json:"workers,omitempty"`     // how many comments to fetch at once
+	Processors int    `json:"processors,omitempty"`  // how many goroutines parse, score and store them
+	QueueDepth int    `json:"queue_depth,omitempty"` // how many items can wait between fetching and processing
+	BatchSize  int    `json:"batch_size,omitempty"`  // how many jobs to store per DB transaction
+}
+
+// NetworkConfig controls the HTTP client.  Zero values mean "use the default".
+type NetworkConfig struct {
+	TimeoutSecs    int     `json:"timeout_secs,omitempty"`
+	UserAgent      string  `json:"user_agent,omitempty"`
+	ProxyURL       string  `json:"proxy_url,omitempty"`
+	MaxIdleConns   int     `json:"max_idle_conns,omitempty"`
+	BaseURL        string  `json:"base_url,omitempty"`         // HN firebase API, e.g. an internal mirror
+	AlgoliaBaseURL string  `json:"algolia_base_url,omitempty"` // HN algolia API, for the "algolia" backend
+	RatePerSec     float64 `json:"rate_per_sec,omitempty"`     // max requests per second, shared by all fetch workers
+	Burst          int     `json:"burst,omitempty"`            // how many requests can go at once before rate_per_sec kicks in
+}
+
+// ProfileConfig is about you, the job seeker, for rules like `remote_compatible` and `location_within_km`
+type ProfileConfig struct {
+	Timezone string  `json:"timezone,omitempty"`  // IANA like "Europe/Berlin", or "UTC+1"
+	Country  string  `json:"country,omitempty"`   // ISO 3166 alpha-2, e.g. "DE"
+	Home     string  `json:"home,omitempty"`      // a city name (see gazetteer) or "lat,lon"
+	RadiusKm float64 `json:"radius_km,omitempty"` // default for `location_within_km` rules
+}
+
+// HomeLatLon returns the coordinates of the configured home, or false if there isn't one
+func (pc *ProfileConfig) HomeLatLon() (float64, float64, bool, error) {
+	if pc.Home == "" {
+		return 0, 0, false, nil
+	}
+	if lat, lon, ok := gazetteer.ParseLatLon(pc.Home); ok {
+		return lat, lon, true, nil
+	}
+	if p := gazetteer.Lookup(pc.Home); p != nil {
+		return p.Lat, p.Lon, true, nil
+	}
+	return 0, 0, false, fmt.Errorf("unknown home %q (use a major city name or `lat,lon`)", pc.Home)
+}
+
+// UTCOffset returns the current offset of the configured timezone in hours, or nil if there isn't one
+func (pc *ProfileConfig) UTCOffset() (*float64, error) {
+	if pc.Timezone == "" {
+		return nil, nil
+	}
+	if v, ok := workmode.ParseOffset(pc.Timezone); ok {
+		return &v, nil
+	}
+	loc, err := time.LoadLocation(pc.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q (use e.g. `Europe/Berlin` or `UTC+1`)", pc.Timezone)
+	}
+	_, secs := time.Now().In(loc).Zone()
+	v := float64(secs) / 3600
+	return &v, nil
+}
+
 type ScoringConfig struct {
 	Rules []ScoringRule `json:"rules"`
 }
@@ -34,13 +106,75 @@ type DisplayConfig struct {
 }
 
 type ScoringRule struct {
-	TextFound   string                `json:"text_found,omitempty"`
-	TextMissing string                `json:"text_missing,omitempty"`
-	Score       int                   `json:"score"`
-	TagsWhy     []string              `json:"tags_why,omitempty"`
-	TagsWhyNot  []string              `json:"tags_why_not,omitempty"`
-	Colorize    *bool                 `json:"colorize,omitempty"` // pointer for default nil instead of false
-	Style       *sanitview.TViewStyle `json:"style,omitempty"`
+	TextFound        string                `json:"text_found,omitempty"`
+	TextMissing      string                `json:"text_missing,omitempty"`
+	Numeric          string                `json:"numeric,omitempty"`            // e.g. "salary_max >= 180000", see NumericCondition
+	WorkMode         string                `json:"work_mode,omitempty"`          // "remote", "hybrid", "onsite" or "unknown"
+	RemoteCompatible *bool                 `json:"remote_compatible,omitempty"`  // whether a remote job allows your profile's location
+	LocationWithinKm *float64              `json:"location_within_km,omitempty"` // 0 means your profile's radius_km
+	Tech             string                `json:"tech,omitempty"`               // a techstack key, e.g. "kubernetes"
+	Field            string                `json:"field,omitempty"`              // match part of the job instead of the whole text
+	RawHTML          bool                  `json:"raw_html,omitempty"`           // match the HTML from HN instead of plain text
+	All              []ScoringRule         `json:"all,omitempty"`                // compound rule: every condition must match
+	Any              []ScoringRule         `json:"any,omitempty"`                // compound rule: at least one condition must match
+	Not              *ScoringRule          `json:"not,omitempty"`                // compound rule: the condition must not match
+	Score            int                   `json:"score,omitempty"`
+	TagsWhy          []string              `json:"tags_why,omitempty"`
+	TagsWhyNot       []string              `json:"tags_why_not,omitempty"`
+	Colorize         *bool                 `json:"colorize,omitempty"` // pointer for default nil instead of false
+	Style            *sanitview.TViewStyle `json:"style,omitempty"`
+}
+
+// NumericFields are the values which numeric rules can compare.  Salaries are annualized.
+var NumericFields = []string{"salary_min", "salary_max"}
+
+// NumericCondition is a parsed `numeric` scoring rule, e.g. "salary_max >= 180000" or "salary_min > 150k"
+type NumericCondition struct {
+	Field string
+	Op    string
+	Value float64
+}
+
+var numericConditionRegex = regexp.MustCompile(`^\s*([a-z_]+)\s*(<=|>=|==|!=|<|>)\s*(-?\d+(?:\.\d+)?)([kK]?)\s*$`)
+
+func ParseNumericCondition(s string) (*NumericCondition, error) {
+	m := numericConditionRegex.FindStringSubmatch(s)
+	if m == nil {
+		return nil, fmt.Errorf("can't parse numeric rule %q (should look like `salary_max >= 180000`)", s)
+	}
+	if !slices.Contains(NumericFields, m[1]) {
+		return nil, fmt.Errorf(
+			"unknown numeric rule field %q (must be one of: %s)", m[1], strings.Join(NumericFields, ", "),
+		)
+	}
+	v, err := strconv.ParseFloat(m[3], 64)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse numeric rule %q: %v", s, err)
+	}
+	if m[4] != "" {
+		v *= 1000
+	}
+	return &NumericCondition{Field: m[1], Op: m[2], Value: v}, nil
+}
+
+// Matches reports whether v satisfies the condition
+func (nc *NumericCondition) Matches(v float64) bool {
+	switch nc.Op {
+	case "<":
+		return v < nc.Value
+	case "<=":
+		return v <= nc.Value
+	case ">":
+		return v > nc.Value
+	case ">=":
+		return v >= nc.Value
+	case "==":
+		return v == nc.Value
+	case "!=":
+		return v != nc.Value
+	default:
+		panic(fmt.Errorf("unhandled numeric operator %q", nc.Op))
+	}
 }
 
 func GetConfig() ConfigObj {
@@ -85,18 +219,27 @@ func loadConfigJSON(j []byte) error {
 		return err
 	}
 	// validate
-	for i, r := range config.Scoring.Rules {
-		if r.TextFound == "" && r.TextMissing == "" {
-			return errors.New("scoring rules must have either `text_found` or `text_missing`")
-		}
-		if r.TextFound != "" && r.TextMissing != "" {
-			return errors.New("scoring rules cannot have both `text_found` and `text_missing`")
-		}
-		if r.TextFound != "" {
-			config.Scoring.Rules[i].TextFound = strings.ToLower(r.TextFound)
-		}
-		if r.TextMissing != "" {
-			config.Scoring.Rules[i].TextMissing = strings.ToLower(r.TextMissing)
+	switch config.Fetch.Backend {
+	case "", "firebase", "algolia":
+	default:
+		return fmt.Errorf("unknown fetch backend %q (must be `firebase` or `algolia`)", config.Fetch.Backend)
+	}
+	fc := config.Fetch
+	if fc.Workers < 0 || fc.Processors < 0 || fc.QueueDepth < 0 || fc.BatchSize < 0 {
+		return errors.New("`workers`, `processors`, `queue_depth` and `batch_size` in `fetch` can't be negative")
+	}
+	if config.Network.Burst < 0 {
+		return errors.New("`burst` in `network` can't be negative")
+	}
+	if _, err := config.Profile.UTCOffset(); err != nil {
+		return err
+	}
+	if _, _, _, err := config.Profile.HomeLatLon(); err != nil {
+		return err
+	}
+	for i := range config.Scoring.Rules {
+		if err := validateRule(&config.Scoring.Rules[i], false); err != nil {
+			return err
 		}
 	}
 	// TODO validate that tags are sane (json-compliant, sqlite-compliant, no spaces)
@@ -105,6 +248,94 @@ func loadConfigJSON(j []byte) error {
 	return nil
 }
 
+// validateRule checks one scoring rule, or one condition inside a compound rule if sub is true
+func validateRule(r *ScoringRule, sub bool) error {
+	numKinds := 0
+	for _, s := range []string{r.TextFound, r.TextMissing, r.Numeric, r.WorkMode, r.Tech} {
+		if s != "" {
+			numKinds++
+		}
+	}
+	for _, compound := range []bool{len(r.All) > 0, len(r.Any) > 0, r.Not != nil} {
+		if compound {
+			numKinds++
+		}
+	}
+	if r.RemoteCompatible != nil {
+		numKinds++
+	}
+	if r.LocationWithinKm != nil {
+		numKinds++
+	}
+	if numKinds != 1 {
+		return errors.New(
+			"scoring rules must have exactly one of `text_found`, `text_missing`, `numeric`, `work_mode`, " +
+				"`remote_compatible`, `location_within_km`, `tech`, `all`, `any` or `not`",
+		)
+	}
+	if r.Field != "" && r.TextFound == "" && r.TextMissing == "" {
+		return errors.New("`field` only applies to `text_found` and `text_missing` scoring rules")
+	}
+	if r.RawHTML && r.TextFound == "" && r.TextMissing == "" {
+		return errors.New("`raw_html` only applies to `text_found` and `text_missing` scoring rules")
+	}
+	if sub && (r.Score != 0 || len(r.TagsWhy) > 0 || len(r.TagsWhyNot) > 0 || r.Colorize != nil || r.Style != nil) {
+		return errors.New("`score`, tags and styles go on the top-level rule, not inside `all`, `any` or `not`")
+	}
+	for _, subRules := range [][]ScoringRule{r.All, r.Any} {
+		for i := range subRules {
+			if err := validateRule(&subRules[i], true); err != nil {
+				return err
+			}
+		}
+	}
+	if r.Not != nil {
+		if err := validateRule(r.Not, true); err != nil {
+			return err
+		}
+	}
+	if r.Numeric != "" {
+		if _, err := ParseNumericCondition(r.Numeric); err != nil {
+			return err
+		}
+	}
+	if r.WorkMode != "" {
+		if _, ok := workmode.ParseMode(r.WorkMode); !ok {
+			return fmt.Errorf(
+				"unknown work_mode %q (must be one of: remote, hybrid, onsite, unknown)", r.WorkMode,
+			)
+		}
+	}
+	if r.Tech != "" && techstack.Get(r.Tech) == nil {
+		return fmt.Errorf("unknown tech %q (see techstack/taxonomy.tsv for the list)", r.Tech)
+	}
+	if r.RemoteCompatible != nil && config.Profile.Timezone == "" && config.Profile.Country == "" {
+		return errors.New("`remote_compatible` scoring rules need your `timezone` and/or `country` in `profile`")
+	}
+	if r.LocationWithinKm != nil {
+		if config.Profile.Home == "" {
+			return errors.New("`location_within_km` scoring rules need your `home` in `profile`")
+		}
+		if *r.LocationWithinKm < 0 || (*r.LocationWithinKm == 0 && config.Profile.RadiusKm <= 0) {
+			return errors.New("`location_within_km` must be a distance, or 0 to use `radius_km` from `profile`")
+		}
+	}
+	isField := slices.Contains(jobheader.FieldNames, r.Field) || slices.Contains(jobheader.ScopeNames, r.Field)
+	if r.Field != "" && !isField {
+		return fmt.Errorf(
+			"unknown scoring rule field %q (must be one of: %s)",
+			r.Field, strings.Join(slices.Concat(jobheader.FieldNames, jobheader.ScopeNames), ", "),
+		)
+	}
+	if r.TextFound != "" {
+		r.TextFound = strings.ToLower(r.TextFound)
+	}
+	if r.TextMissing != "" {
+		r.TextMissing = strings.ToLower(r.TextMissing)
+	}
+	return nil
+}
+
 func DefaultConfigFileContents() []byte {
 	// I want the generated rules to be pleasant to edit, which means one rule per line.
 	// There's no way to get the standard json package to marshal this way.
@@ -137,25 +368,20 @@ func DefaultConfigFileContents() []byte {
 			TagsWhy:   []string{"level"},
 		},
 		{
-			TextFound: "(?i)aws",
-			Score:     1,
-			TagsWhy:   []string{"tech"},
+			Tech:    "aws",
+			Score:   1,
+			TagsWhy: []string{"tech"},
 		},
 		{
-			TextFound: "(?i)\\brust\\b",
-			Score:     1,
-			TagsWhy:   []string{"tech", "memecred"},
-			Style:     &sanitview.TViewStyle{Fg: "deeppink"},
+			Tech:    "rust",
+			Score:   1,
+			TagsWhy: []string{"tech", "memecred"},
+			Style:   &sanitview.TViewStyle{Fg: "deeppink"},
 		},
 		{
-			TextFound: "(?i)golang",
-			Score:     1,
-			TagsWhy:   []string{"tech"},
-		},
-		{
-			TextFound: "(?i)\\bgo\\b",
-			Score:     1,
-			TagsWhy:   []string{"tech"},
+			Tech:    "go",
+			Score:   1,
+			TagsWhy: []string{"tech"},
 		},
 		{
 			TextFound: "(?i)open.?source",
@@ -173,9 +399,19 @@ func DefaultConfigFileContents() []byte {
 			TagsWhy:   []string{"values"},
 		},
 		{
-			TextMissing: "(?i)remote",
-			Score:       -100,
-			TagsWhyNot:  []string{"onsite", "fsckbezos"},
+			WorkMode:   "onsite",
+			Score:      -100,
+			TagsWhyNot: []string{"onsite", "fsckbezos"},
+		},
+		{
+			WorkMode:   "unknown",
+			Score:      -100,
+			TagsWhyNot: []string{"onsite"},
+		},
+		{
+			WorkMode:   "hybrid",
+			Score:      -2,
+			TagsWhyNot: []string{"hybrid"},
 		},
 	}
 
@@ -203,6 +439,27 @@ func DefaultConfigFileContents() []byte {
 				escapeString(sr.TextMissing),
 			))
 		}
+		if sr.Numeric != "" {
+			elems = append(elems, fmt.Sprintf(`"numeric": "%s"`, sr.Numeric))
+		}
+		if sr.WorkMode != "" {
+			elems = append(elems, fmt.Sprintf(`"work_mode": "%s"`, sr.WorkMode))
+		}
+		if sr.RemoteCompatible != nil {
+			elems = append(elems, fmt.Sprintf(`"remote_compatible": %t`, *sr.RemoteCompatible))
+		}
+		if sr.LocationWithinKm != nil {
+			elems = append(elems, fmt.Sprintf(`"location_within_km": %g`, *sr.LocationWithinKm))
+		}
+		if sr.Tech != "" {
+			elems = append(elems, fmt.Sprintf(`"tech": "%s"`, sr.Tech))
+		}
+		if sr.Field != "" {
+			elems = append(elems, fmt.Sprintf(`"field": "%s"`, sr.Field))
+		}
+		if sr.RawHTML {
+			elems = append(elems, `"raw_html": true`)
+		}
 		elems = append(elems, fmt.Sprintf(`"score": %d`, sr.Score))
 		if len(sr.TagsWhy) > 0 {
 			var quoted []string
@@ -257,6 +514,18 @@ func DefaultConfigFileContents() []byte {
   "cache": {
     "ttl_secs": 86400
   },
+  "fetch": {
+    "backend": "firebase"
+  },
+  "network": {
+    "timeout_secs": 30
+  },
+  "profile": {
+    "timezone": "",
+    "country": "",
+    "home": "",
+    "radius_km": 50
+  },
   "scoring": {
     "rules": [
 %s
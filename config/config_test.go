@@ -12,6 +12,15 @@ func TestDefaultContents(t *testing.T) {
 		Cache: CacheConfig{
 			TTLSecs: 86400,
 		},
+		Fetch: FetchConfig{
+			Backend: "firebase",
+		},
+		Network: NetworkConfig{
+			TimeoutSecs: 30,
+		},
+		Profile: ProfileConfig{
+			RadiusKm: 50,
+		},
 		Scoring: ScoringConfig{
 			Rules: []ScoringRule{
 				{
@@ -40,25 +49,20 @@ func TestDefaultContents(t *testing.T) {
 					TagsWhy:   []string{"level"},
 				},
 				{
-					TextFound: "(?i)aws",
-					Score:     1,
-					TagsWhy:   []string{"tech"},
-				},
-				{
-					TextFound: "(?i)\\brust\\b",
-					Score:     1,
-					TagsWhy:   []string{"tech", "memecred"},
-					Style:     &sanitview.TViewStyle{Fg: "deeppink"},
+					Tech:    "aws",
+					Score:   1,
+					TagsWhy: []string{"tech"},
 				},
 				{
-					TextFound: "(?i)golang",
-					Score:     1,
-					TagsWhy:   []string{"tech"},
+					Tech:    "rust",
+					Score:   1,
+					TagsWhy: []string{"tech", "memecred"},
+					Style:   &sanitview.TViewStyle{Fg: "deeppink"},
 				},
 				{
-					TextFound: "(?i)\\bgo\\b",
-					Score:     1,
-					TagsWhy:   []string{"tech"},
+					Tech:    "go",
+					Score:   1,
+					TagsWhy: []string{"tech"},
 				},
 				{
 					TextFound: "(?i)open.?source",
@@ -76,9 +80,19 @@ func TestDefaultContents(t *testing.T) {
 					TagsWhy:   []string{"values"},
 				},
 				{
-					TextMissing: "(?i)remote",
-					Score:       -100,
-					TagsWhyNot:  []string{"onsite", "fsckbezos"},
+					WorkMode:   "onsite",
+					Score:      -100,
+					TagsWhyNot: []string{"onsite", "fsckbezos"},
+				},
+				{
+					WorkMode:   "unknown",
+					Score:      -100,
+					TagsWhyNot: []string{"onsite"},
+				},
+				{
+					WorkMode:   "hybrid",
+					Score:      -2,
+					TagsWhyNot: []string{"hybrid"},
 				},
 			},
 		},
@@ -99,3 +113,70 @@ func TestDefaultContents(t *testing.T) {
 		)
 	}
 }
+
+func TestValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		wantErr bool
+	}{
+		{"Valid", `{"scoring": {"rules": [{"text_found": "rust", "score": 1}]}}`, false},
+		{"NoText", `{"scoring": {"rules": [{"score": 1}]}}`, true},
+		{"BothTexts", `{"scoring": {"rules": [{"text_found": "a", "text_missing": "b", "score": 1}]}}`, true},
+		{"BadBackend", `{"fetch": {"backend": "carrier pigeon"}}`, true},
+		{"FetchTuning", `{"fetch": {"workers": 8, "processors": 2, "queue_depth": 50, "batch_size": 100}}`, false},
+		{"NegativeWorkers", `{"fetch": {"workers": -1}}`, true},
+		{"Field", `{"scoring": {"rules": [{"text_found": "berlin", "field": "location", "score": 1}]}}`, false},
+		{"Scope", `{"scoring": {"rules": [{"text_found": "(?i)senior", "field": "header", "score": 1}]}}`, false},
+		{"RawHTML", `{"scoring": {"rules": [{"text_found": "<i>urgent</i>", "raw_html": true, "score": -1}]}}`, false},
+		{"RawHTMLOnTech", `{"scoring": {"rules": [{"tech": "go", "raw_html": true, "score": 1}]}}`, true},
+		{"BadField", `{"scoring": {"rules": [{"text_found": "berlin", "field": "planet", "score": 1}]}}`, true},
+		{"Numeric", `{"scoring": {"rules": [{"numeric": "salary_max >= 180k", "score": 3}]}}`, false},
+		{"NumericAndText", `{"scoring": {"rules": [{"numeric": "salary_max >= 1", "text_found": "a", "score": 3}]}}`, true},
+		{"BadNumeric", `{"scoring": {"rules": [{"numeric": "salary_max is big", "score": 3}]}}`, true},
+		{"WorkMode", `{"scoring": {"rules": [{"work_mode": "hybrid", "score": -2}]}}`, false},
+		{"BadWorkMode", `{"scoring": {"rules": [{"work_mode": "moon base", "score": -2}]}}`, true},
+		{"RemoteCompatibleNoProfile", `{"scoring": {"rules": [{"remote_compatible": false, "score": -50}]}}`, true},
+		{"RemoteCompatible", `{"profile": {"timezone": "Europe/Berlin", "country": "DE"}, "scoring": {"rules": [{"remote_compatible": false, "score": -50}]}}`, false},
+		{"BadTimezone", `{"profile": {"timezone": "Mars/Olympus_Mons"}}`, true},
+		{"FieldOnWorkMode", `{"scoring": {"rules": [{"work_mode": "hybrid", "field": "role", "score": -2}]}}`, true},
+		{"LocationNoHome", `{"scoring": {"rules": [{"location_within_km": 50, "score": 2}]}}`, true},
+		{"Location", `{"profile": {"home": "Berlin"}, "scoring": {"rules": [{"location_within_km": 50, "score": 2}]}}`, false},
+		{"LocationDefaultRadius", `{"profile": {"home": "52.5,13.4", "radius_km": 30}, "scoring": {"rules": [{"location_within_km": 0, "score": 2}]}}`, false},
+		{"LocationNoRadius", `{"profile": {"home": "Berlin"}, "scoring": {"rules": [{"location_within_km": 0, "score": 2}]}}`, true},
+		{"BadHome", `{"profile": {"home": "Atlantis"}}`, true},
+		{"Tech", `{"scoring": {"rules": [{"tech": "kubernetes", "score": 1}]}}`, false},
+		{"BadTech", `{"scoring": {"rules": [{"tech": "cobol on cogs", "score": 1}]}}`, true},
+		{"TechAndField", `{"scoring": {"rules": [{"tech": "go", "field": "role", "score": 1}]}}`, true},
+		{"All", `{"scoring": {"rules": [{"all": [{"tech": "rust"}, {"work_mode": "remote"}, {"not": {"text_found": "us only"}}], "score": 3}]}}`, false},
+		{"Any", `{"scoring": {"rules": [{"any": [{"text_found": "sre"}, {"text_found": "devops", "field": "role"}], "score": 1}]}}`, false},
+		{"AllAndText", `{"scoring": {"rules": [{"all": [{"tech": "rust"}], "text_found": "a", "score": 3}]}}`, true},
+		{"EmptyAll", `{"scoring": {"rules": [{"all": [], "score": 3}]}}`, true},
+		{"BadSubRule", `{"scoring": {"rules": [{"any": [{"tech": "cobol on cogs"}], "score": 1}]}}`, true},
+		{"BadNotRule", `{"scoring": {"rules": [{"not": {"text_found": "a", "text_missing": "b"}, "score": 1}]}}`, true},
+		{"ScoreInSubRule", `{"scoring": {"rules": [{"all": [{"tech": "rust", "score": 5}], "score": 1}]}}`, true},
+		{"BadNumericField", `{"scoring": {"rules": [{"numeric": "vacation_days > 30", "score": 3}]}}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := loadConfigJSON([]byte(tt.json))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("wantErr %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestNumericCondition(t *testing.T) {
+	nc, err := ParseNumericCondition("salary_max >= 180k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &NumericCondition{Field: "salary_max", Op: ">=", Value: 180000}
+	if !reflect.DeepEqual(expected, nc) {
+		t.Errorf("expected %+v, got %+v", expected, nc)
+	}
+	if !nc.Matches(180000) || nc.Matches(179999) {
+		t.Error("wrong comparison")
+	}
+}
@@ -11,6 +11,9 @@ import (
 	//_ "github.com/ncruces/go-sqlite3/driver" //sqlite3
 	//_ "github.com/ncruces/go-sqlite3/embed"
 	"github.com/mwinters0/hnjobs/hn"
+	"github.com/mwinters0/hnjobs/jobheader"
+	"github.com/mwinters0/hnjobs/salary"
+	"github.com/mwinters0/hnjobs/workmode"
 	_ "modernc.org/sqlite" //sqlite
 	"strconv"
 	"sync"
@@ -47,6 +50,11 @@ func OpenDB(filePath string) error {
 	if err != nil {
 		return fmt.Errorf("error opening DB: %v", err)
 	}
+	store.jobUpsert = nil // prepared on the previous DB, if any
+	err = migrate()
+	if err != nil {
+		return fmt.Errorf("error migrating DB: %v", err)
+	}
 	return nil
 }
 
@@ -82,8 +90,10 @@ func GetAllStories() ([]*hn.Story, error) {
 	return stories, nil
 }
 
-func GetLatestStory() (*hn.Story, error) {
-	storyRow := store.db.QueryRow(storySelect + "ORDER BY id DESC LIMIT 1")
+// GetLatestStory returns the newest story from the source (see app.Source), so that e.g. fetching a freelancer thread
+// doesn't replace the hiring thread in the UI
+func GetLatestStory(source string) (*hn.Story, error) {
+	storyRow := store.db.QueryRow(storySelect+"WHERE source = ? ORDER BY id DESC LIMIT 1", source)
 	story, err := unmarshalStoryRow(storyRow)
 	if errors.Is(err, sql.ErrNoRows) {
 		return story, ErrNoResults
@@ -96,24 +106,42 @@ func GetLatestStory() (*hn.Story, error) {
 
 func DeleteStoryAndJobsByStoryID(id int) error {
 	store.writeMutex.Lock()
-	_, err := store.db.Exec(`DELETE FROM hnstories WHERE id = ?`, strconv.Itoa(id))
+	defer store.writeMutex.Unlock()
+	tx, err := store.db.Begin()
 	if err != nil {
 		return fmt.Errorf("error deleting story: %v", err)
 	}
-	_, err = store.db.Exec(`DELETE FROM hnjobs WHERE parent = ?`, strconv.Itoa(id))
+	deletes := []struct {
+		what  string
+		query string
+	}{
+		{"story", `DELETE FROM hnstories WHERE id = ?`},
+		{"job tech", `DELETE FROM job_tech WHERE job_id IN (SELECT id FROM hnjobs WHERE parent = ?)`},
+		{"job rule hits", `DELETE FROM job_rule_hits WHERE job_id IN (SELECT id FROM hnjobs WHERE parent = ?)`},
+		{"job revisions", `DELETE FROM job_revisions WHERE job_id IN (SELECT id FROM hnjobs WHERE parent = ?)`},
+		{"jobs", `DELETE FROM hnjobs WHERE parent = ?`},
+		{"rejects", `DELETE FROM hnrejects WHERE parent = ?`},
+	}
+	for _, d := range deletes {
+		_, err = tx.Exec(d.query, strconv.Itoa(id))
+		if err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("error deleting %s: %v", d.what, err)
+		}
+	}
+	err = tx.Commit()
 	if err != nil {
-		return fmt.Errorf("error deleting jobs: %v", err)
+		return fmt.Errorf("error deleting story: %v", err)
 	}
-	store.writeMutex.Unlock()
 	return nil
 }
 
-const storySelect = "SELECT id, kids, time, title, fetched_time FROM hnstories "
+const storySelect = "SELECT id, kids, time, title, fetched_time, source FROM hnstories "
 
 func unmarshalStoryRow(row scannableRow) (*hn.Story, error) {
 	s := hn.Story{}
 	kids := sql.NullString{}
-	err := row.Scan(&s.Id, &kids, &s.Time, &s.Title, &s.FetchedTime)
+	err := row.Scan(&s.Id, &kids, &s.Time, &s.Title, &s.FetchedTime, &s.Source)
 	if err != nil {
 		return &hn.Story{}, err
 	}
@@ -135,10 +163,11 @@ func UpsertStory(s *hn.Story) error {
 	}
 	store.writeMutex.Lock()
 	_, err = store.db.Exec(
-		`INSERT INTO hnstories (id, kids, time, title, fetched_time) VALUES (?, ?, ?, ?, ?)
+		`INSERT INTO hnstories (id, kids, time, title, fetched_time, source) VALUES (?, ?, ?, ?, ?, ?)
 	ON CONFLICT (id) DO UPDATE SET
-	kids = excluded.kids, time = excluded.time, title = excluded.title, fetched_time = excluded.fetched_time`,
-		s.Id, nullableString(kids), s.Time, s.Title, s.FetchedTime,
+	kids = excluded.kids, time = excluded.time, title = excluded.title, fetched_time = excluded.fetched_time,
+	source = excluded.source`,
+		s.Id, nullableString(kids), s.Time, s.Title, s.FetchedTime, s.Source,
 	)
 	store.writeMutex.Unlock()
 	if err != nil {
@@ -150,40 +179,97 @@ func UpsertStory(s *hn.Story) error {
 // === table: hnjobs
 
 type Job struct {
-	Id             int
-	Parent         int
-	Company        string
-	Text           string
-	Time           int64
-	GoTime         time.Time `json:"-"`
-	FetchedTime    int64
-	FetchedGoTime  time.Time `json:"-"`
-	ReviewedTime   int64
-	ReviewedGoTime time.Time `json:"-"`
-	Why            []string
-	WhyNot         []string
-	Score          int
-	Read           bool
-	Interested     bool
-	Priority       bool
-	Applied        bool
+	Id              int
+	Parent          int
+	Company         string
+	Text            string
+	Time            int64
+	GoTime          time.Time `json:"-"`
+	FetchedTime     int64
+	FetchedGoTime   time.Time `json:"-"`
+	ReviewedTime    int64
+	ReviewedGoTime  time.Time           `json:"-"`
+	WithdrawnTime   int64               // when we noticed the poster deleted the job; 0 if still live
+	WithdrawnGoTime time.Time           `json:"-"`
+	Header          jobheader.JobHeader // Header.Company is the same as Company
+	Salary          salary.Salary
+	WorkMode        workmode.WorkMode
+	Places          []string // gazetteer keys
+	Tech            []string // techstack keys, stored in job_tech
+	Why             []string
+	WhyNot          []string
+	Score           int
+	RuleHits        []*RuleHit // the rules which added up to Score, stored in job_rule_hits
+	Read            bool
+	Interested      bool
+	Priority        bool
+	Applied         bool
+}
+
+// RuleHit is a scoring rule which matched a job
+type RuleHit struct {
+	RuleIndex int      // position in the config's rules when the job was scored
+	Rule      string   // the rule as JSON, since the index changes when the config is edited
+	Points    int      // the rule's score
+	Matches   [][2]int `json:",omitempty"` // byte offsets of what the rule's text or tech conditions matched in the plain text
 }
 
 func UpsertJob(job *Job) error {
+	return UpsertJobs([]*Job{job})
+}
+
+// UpsertJobs stores the jobs in a single transaction, which is a lot faster than one at a time
+func UpsertJobs(jobs []*Job) error {
+	store.writeMutex.Lock() // before the lazy Prepare, since the fetch processors upsert concurrently
+	defer store.writeMutex.Unlock()
 	if store.jobUpsert == nil {
 		store.jobUpsert, _ = store.db.Prepare(
 			`INSERT INTO hnjobs (
 			id, parent, company, text, time, fetched_time,
 			reviewed_time, score, why, why_not,
-			read, interested, priority, applied
-			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			read, interested, priority, applied, withdrawn_time,
+			header_role, header_location, header_remote, header_employment,
+			header_salary, header_visa, header_url, header_extra,
+			salary_min, salary_max, currency, period,
+			work_mode, work_regions, tz_min, tz_max, places
+			) VALUES (
+			?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?
+			)
 			ON CONFLICT (id) DO UPDATE SET
 			company=excluded.company, text=excluded.text, time=excluded.time, fetched_time=excluded.fetched_time,
 			reviewed_time=excluded.reviewed_time, score=excluded.score, why=excluded.why, why_not=excluded.why_not,
-			read=excluded.read, interested=excluded.interested, priority=excluded.priority, applied=excluded.applied 
+			read=excluded.read, interested=excluded.interested, priority=excluded.priority, applied=excluded.applied,
+			withdrawn_time=excluded.withdrawn_time,
+			header_role=excluded.header_role, header_location=excluded.header_location,
+			header_remote=excluded.header_remote, header_employment=excluded.header_employment,
+			header_salary=excluded.header_salary, header_visa=excluded.header_visa, header_url=excluded.header_url,
+			header_extra=excluded.header_extra,
+			salary_min=excluded.salary_min, salary_max=excluded.salary_max, currency=excluded.currency,
+			period=excluded.period,
+			work_mode=excluded.work_mode, work_regions=excluded.work_regions, tz_min=excluded.tz_min,
+			tz_max=excluded.tz_max, places=excluded.places
 			`,
 		)
 	}
+	tx, err := store.db.Begin()
+	if err != nil {
+		return fmt.Errorf("upsert failed: %v", err)
+	}
+	for _, job := range jobs {
+		err = upsertJob(tx, job)
+		if err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("upsert failed: %v", err)
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("upsert failed: %v", err)
+	}
+	return nil
+}
+
+func upsertJob(tx *sql.Tx, job *Job) error {
 	why, err := json.Marshal(job.Why)
 	if err != nil {
 		log.Fatal(err)
@@ -192,17 +278,81 @@ func UpsertJob(job *Job) error {
 	if err != nil {
 		log.Fatal(err)
 	}
-	store.writeMutex.Lock()
-	_, err = store.jobUpsert.Exec(
+	h := &job.Header
+	extra, err := json.Marshal(h.Extra)
+	if err != nil {
+		log.Fatal(err)
+	}
+	regions, err := json.Marshal(job.WorkMode.Regions)
+	if err != nil {
+		log.Fatal(err)
+	}
+	places, err := json.Marshal(job.Places)
+	if err != nil {
+		log.Fatal(err)
+	}
+	tzMin := sql.NullFloat64{}
+	tzMax := sql.NullFloat64{}
+	if job.WorkMode.TZ != nil {
+		tzMin = sql.NullFloat64{Float64: job.WorkMode.TZ.Min, Valid: true}
+		tzMax = sql.NullFloat64{Float64: job.WorkMode.TZ.Max, Valid: true}
+	}
+	_, err = tx.Stmt(store.jobUpsert).Exec(
 		job.Id, job.Parent, job.Company, job.Text, job.Time, job.FetchedTime,
 		job.ReviewedTime, job.Score, nullableString(why), nullableString(whyNot),
-		job.Read, job.Interested, job.Priority, job.Applied,
+		job.Read, job.Interested, job.Priority, job.Applied, nullableInt64(job.WithdrawnTime),
+		h.Role, h.Location, h.Remote, h.Employment,
+		h.Salary, h.Visa, h.URL, nullableString(extra),
+		nullableInt64(job.Salary.Min), nullableInt64(job.Salary.Max), job.Salary.Currency, job.Salary.Period,
+		job.WorkMode.Mode, nullableString(regions), tzMin, tzMax, nullableString(places),
 	)
-	store.writeMutex.Unlock()
-	if err != nil {
-		return fmt.Errorf("upsert failed: %v", err)
+	if err == nil {
+		_, err = tx.Exec(`DELETE FROM job_tech WHERE job_id = ?`, job.Id)
 	}
-	return nil
+	for _, t := range job.Tech {
+		if err != nil {
+			break
+		}
+		_, err = tx.Exec(`INSERT INTO job_tech (job_id, tech) VALUES (?, ?)`, job.Id, t)
+	}
+	if err == nil {
+		_, err = tx.Exec(`DELETE FROM job_rule_hits WHERE job_id = ?`, job.Id)
+	}
+	for _, hit := range job.RuleHits {
+		if err != nil {
+			break
+		}
+		matches, jsonErr := json.Marshal(hit.Matches)
+		if jsonErr != nil {
+			log.Fatal(jsonErr)
+		}
+		_, err = tx.Exec(
+			`INSERT INTO job_rule_hits (job_id, rule_index, rule, points, matches) VALUES (?, ?, ?, ?, ?)`,
+			job.Id, hit.RuleIndex, hit.Rule, hit.Points, nullableString(matches),
+		)
+	}
+	if err == nil {
+		// new revision if the text changed since the latest one
+		_, err = tx.Exec(
+			`INSERT INTO job_revisions (job_id, fetched_time, text) SELECT ?, ?, ?
+			WHERE ? IS NOT (SELECT text FROM job_revisions WHERE job_id = ? ORDER BY rowid DESC LIMIT 1)`,
+			job.Id, job.FetchedTime, job.Text, job.Text, job.Id,
+		)
+	}
+	if err == nil {
+		// e.g. it was rejected before being edited, or the user promoted it
+		_, err = tx.Exec(`DELETE FROM hnrejects WHERE id = ?`, job.Id)
+	}
+	return err
+}
+
+func GetJobById(id int) (*Job, error) {
+	row := store.db.QueryRow(jobSelect+"WHERE id = ?", id)
+	job, err := unmarshalJobRow(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return job, ErrNoResults
+	}
+	return job, err
 }
 
 func GetAllJobsByStoryId(id int, co JobOrder) ([]*Job, error) {
@@ -258,7 +408,15 @@ func GetAllJobIDsByStoryID(storyID int) *[]int {
 
 const jobSelect = `SELECT id, parent, company, text, time, fetched_time,
 	reviewed_time, why, why_not, score,
-	read, interested, priority, applied FROM hnjobs
+	read, interested, priority, applied, withdrawn_time,
+	header_role, header_location, header_remote, header_employment,
+	header_salary, header_visa, header_url, header_extra,
+	salary_min, salary_max, currency, period,
+	work_mode, work_regions, tz_min, tz_max, places,
+	(SELECT json_group_array(tech) FROM job_tech WHERE job_id = hnjobs.id),
+	(SELECT json_group_array(json_object(
+		'RuleIndex', rule_index, 'Rule', rule, 'Points', points, 'Matches', json(matches)
+	)) FROM (SELECT * FROM job_rule_hits WHERE job_id = hnjobs.id ORDER BY rule_index)) FROM hnjobs
 `
 
 func unmarshalJobRow(row scannableRow) (*Job, error) {
@@ -266,10 +424,26 @@ func unmarshalJobRow(row scannableRow) (*Job, error) {
 	reviewedTime := sql.NullInt64{}
 	why := sql.NullString{}
 	whyNot := sql.NullString{}
+	withdrawnTime := sql.NullInt64{}
+	extra := sql.NullString{}
+	salaryMin := sql.NullInt64{}
+	salaryMax := sql.NullInt64{}
+	regions := sql.NullString{}
+	tzMin := sql.NullFloat64{}
+	tzMax := sql.NullFloat64{}
+	places := sql.NullString{}
+	tech := ""
+	ruleHits := ""
+	h := &job.Header
 	err := row.Scan(
 		&job.Id, &job.Parent, &job.Company, &job.Text, &job.Time, &job.FetchedTime,
 		&reviewedTime, &why, &whyNot, &job.Score,
-		&job.Read, &job.Interested, &job.Priority, &job.Applied,
+		&job.Read, &job.Interested, &job.Priority, &job.Applied, &withdrawnTime,
+		&h.Role, &h.Location, &h.Remote, &h.Employment,
+		&h.Salary, &h.Visa, &h.URL, &extra,
+		&salaryMin, &salaryMax, &job.Salary.Currency, &job.Salary.Period,
+		&job.WorkMode.Mode, &regions, &tzMin, &tzMax, &places,
+		&tech, &ruleHits,
 	)
 	if err != nil {
 		return &Job{}, err
@@ -278,6 +452,48 @@ func unmarshalJobRow(row scannableRow) (*Job, error) {
 		job.ReviewedTime = reviewedTime.Int64
 		job.ReviewedGoTime = time.Unix(job.ReviewedTime, 0)
 	}
+	if withdrawnTime.Valid {
+		job.WithdrawnTime = withdrawnTime.Int64
+		job.WithdrawnGoTime = time.Unix(job.WithdrawnTime, 0)
+	}
+	h.Company = job.Company
+	job.Salary.Min = salaryMin.Int64 // 0 if NULL
+	job.Salary.Max = salaryMax.Int64
+	if regions.Valid {
+		err = json.Unmarshal([]byte(regions.String), &job.WorkMode.Regions)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	if places.Valid {
+		err = json.Unmarshal([]byte(places.String), &job.Places)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	err = json.Unmarshal([]byte(tech), &job.Tech)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(job.Tech) == 0 {
+		job.Tech = nil // json_group_array gives "[]"
+	}
+	err = json.Unmarshal([]byte(ruleHits), &job.RuleHits)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(job.RuleHits) == 0 {
+		job.RuleHits = nil
+	}
+	if tzMin.Valid && tzMax.Valid {
+		job.WorkMode.TZ = &workmode.TZWindow{Min: tzMin.Float64, Max: tzMax.Float64}
+	}
+	if extra.Valid {
+		err = json.Unmarshal([]byte(extra.String), &h.Extra)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
 	if why.Valid {
 		err = json.Unmarshal([]byte(why.String), &job.Why)
 		if err != nil {
@@ -295,8 +511,120 @@ func unmarshalJobRow(row scannableRow) (*Job, error) {
 	return &job, nil
 }
 
+// === table: job_revisions
+
+// Revision is one version of a job's text
+type Revision struct {
+	JobId         int
+	FetchedTime   int64     // when we first saw this version
+	FetchedGoTime time.Time `json:"-"`
+	Text          string
+}
+
+// GetRevisionsByJobId returns the job's revisions, oldest first.  The last one is the job's current text.
+func GetRevisionsByJobId(id int) ([]*Revision, error) {
+	return queryRevisions(revisionSelect+"WHERE job_id = ? ORDER BY rowid ASC", id)
+}
+
+// GetRevisionsByStoryId returns the revisions of all the story's jobs, oldest first
+func GetRevisionsByStoryId(id int) ([]*Revision, error) {
+	return queryRevisions(
+		revisionSelect+"WHERE job_id IN (SELECT id FROM hnjobs WHERE parent = ?) ORDER BY rowid ASC", id,
+	)
+}
+
+const revisionSelect = "SELECT job_id, fetched_time, text FROM job_revisions "
+
+func queryRevisions(query string, args ...any) ([]*Revision, error) {
+	var revisions []*Revision
+	rows, err := store.db.Query(query, args...)
+	if err != nil {
+		return revisions, fmt.Errorf("couldn't query: %v", err)
+	}
+	for rows.Next() {
+		r := Revision{}
+		err = rows.Scan(&r.JobId, &r.FetchedTime, &r.Text)
+		if err != nil {
+			return revisions, fmt.Errorf("couldn't unmarshal revision: %v", err)
+		}
+		r.FetchedGoTime = time.Unix(r.FetchedTime, 0)
+		revisions = append(revisions, &r)
+	}
+	return revisions, nil
+}
+
+// === table: hnrejects
+
+// Reject is a top-level comment which we couldn't turn into a Job, e.g. a meta comment or one without a company name
+type Reject struct {
+	Id          int
+	Parent      int
+	Text        string
+	Time        int64
+	GoTime      time.Time `json:"-"`
+	FetchedTime int64
+	Reason      string
+}
+
+func UpsertReject(r *Reject) error {
+	store.writeMutex.Lock()
+	_, err := store.db.Exec(
+		`INSERT INTO hnrejects (id, parent, text, time, fetched_time, reason) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+		text=excluded.text, time=excluded.time, fetched_time=excluded.fetched_time, reason=excluded.reason`,
+		r.Id, r.Parent, r.Text, r.Time, r.FetchedTime, r.Reason,
+	)
+	store.writeMutex.Unlock()
+	if err != nil {
+		return fmt.Errorf("upsert failed: %v", err)
+	}
+	return nil
+}
+
+const rejectSelect = "SELECT id, parent, text, time, fetched_time, reason FROM hnrejects "
+
+func GetRejectById(id int) (*Reject, error) {
+	row := store.db.QueryRow(rejectSelect+"WHERE id = ?", id)
+	r, err := unmarshalRejectRow(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return r, ErrNoResults
+	}
+	return r, err
+}
+
+func GetAllRejectsByStoryId(id int) ([]*Reject, error) {
+	var rejects []*Reject
+	rows, err := store.db.Query(rejectSelect+"WHERE parent = ? ORDER BY time ASC", id)
+	if err != nil {
+		return rejects, fmt.Errorf("couldn't query: %v", err)
+	}
+	for rows.Next() {
+		r, err := unmarshalRejectRow(rows)
+		if err != nil {
+			return rejects, fmt.Errorf("couldn't unmarshal reject: %v", err)
+		}
+		rejects = append(rejects, r)
+	}
+	return rejects, nil
+}
+
+func unmarshalRejectRow(row scannableRow) (*Reject, error) {
+	r := Reject{}
+	err := row.Scan(&r.Id, &r.Parent, &r.Text, &r.Time, &r.FetchedTime, &r.Reason)
+	if err != nil {
+		return &Reject{}, err
+	}
+	r.GoTime = time.Unix(r.Time, 0)
+	return &r, nil
+}
+
 // === util
 
+// store 0 as NULL
+func nullableInt64(i int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: i, Valid: i != 0}
+}
+
 // avoid inserting "null" for empty strings
 func nullableString(in []byte) sql.NullString {
 	s := string(in)
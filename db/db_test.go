@@ -0,0 +1,48 @@
+package db
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestUpsertJobRuleHits(t *testing.T) {
+	err := NewDB(filepath.Join(t.TempDir(), "hnjobs.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	job := &Job{Id: 101, Parent: 100, Company: "Acme", Text: "Acme | SRE<p>We use golang", Score: 3}
+
+	steps := []struct {
+		name string
+		hits []*RuleHit
+	}{
+		{"stored", []*RuleHit{
+			{RuleIndex: 0, Rule: `{"text_found":"SRE","score":2}`, Points: 2, Matches: [][2]int{{7, 10}}},
+			{RuleIndex: 3, Rule: `{"numeric":"salary_min >= 100k","score":1}`, Points: 1},
+		}},
+		{"replaced", []*RuleHit{
+			{RuleIndex: 1, Rule: `{"tech":"go","score":5}`, Points: 5, Matches: [][2]int{{19, 25}}},
+		}},
+		{"removed", nil},
+	}
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			job.RuleHits = step.hits
+			err := UpsertJob(job)
+			if err != nil {
+				t.Fatal(err)
+			}
+			jobs, err := GetAllJobsByStoryId(job.Parent, OrderNone)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(jobs) != 1 {
+				t.Fatalf("Expected 1 job, got %d", len(jobs))
+			}
+			if !reflect.DeepEqual(jobs[0].RuleHits, step.hits) {
+				t.Errorf("Expected:\n  %#v\ngot:\n  %#v", step.hits, jobs[0].RuleHits)
+			}
+		})
+	}
+}
@@ -1,20 +1,117 @@
 package db
 
-import _ "embed"
+import (
+	"database/sql"
+	_ "embed"
+	"fmt"
+)
 
+// schema.sql is the original (version 0) schema.  Changes since then are in migrations.
+//
 //go:embed schema.sql
 var newDBSchema string
 
+// migrations bring the schema up to date.  After applying migrations[i] the DB is at version i+1, which we store in
+// sqlite's user_version.  Only ever append to this list.
+var migrations = []string{
+	// 1: which app.Source found the story
+	`ALTER TABLE hnstories ADD COLUMN source TEXT NOT NULL DEFAULT 'whoishiring';`,
+	// 2: the poster deleted the job
+	`ALTER TABLE hnjobs ADD COLUMN withdrawn_time INTEGER;`,
+	// 3: the parsed header line (see jobheader)
+	`ALTER TABLE hnjobs ADD COLUMN header_role TEXT NOT NULL DEFAULT '';
+	ALTER TABLE hnjobs ADD COLUMN header_location TEXT NOT NULL DEFAULT '';
+	ALTER TABLE hnjobs ADD COLUMN header_remote TEXT NOT NULL DEFAULT '';
+	ALTER TABLE hnjobs ADD COLUMN header_employment TEXT NOT NULL DEFAULT '';
+	ALTER TABLE hnjobs ADD COLUMN header_salary TEXT NOT NULL DEFAULT '';
+	ALTER TABLE hnjobs ADD COLUMN header_visa TEXT NOT NULL DEFAULT '';
+	ALTER TABLE hnjobs ADD COLUMN header_url TEXT NOT NULL DEFAULT '';
+	ALTER TABLE hnjobs ADD COLUMN header_extra TEXT;`,
+	// 4: the parsed salary (see salary)
+	`ALTER TABLE hnjobs ADD COLUMN salary_min INTEGER;
+	ALTER TABLE hnjobs ADD COLUMN salary_max INTEGER;
+	ALTER TABLE hnjobs ADD COLUMN currency TEXT NOT NULL DEFAULT '';
+	ALTER TABLE hnjobs ADD COLUMN period TEXT NOT NULL DEFAULT '';`,
+	// 5: remote / hybrid / onsite (see workmode)
+	`ALTER TABLE hnjobs ADD COLUMN work_mode TEXT NOT NULL DEFAULT '';
+	ALTER TABLE hnjobs ADD COLUMN work_regions TEXT;
+	ALTER TABLE hnjobs ADD COLUMN tz_min REAL;
+	ALTER TABLE hnjobs ADD COLUMN tz_max REAL;`,
+	// 6: where the job is (see gazetteer)
+	`ALTER TABLE hnjobs ADD COLUMN places TEXT;`,
+	// 7: technologies mentioned in the job (see techstack)
+	`CREATE TABLE job_tech (
+	job_id INTEGER NOT NULL,
+	tech TEXT NOT NULL,
+	PRIMARY KEY (job_id, tech)
+	);`,
+	// 8: top-level comments which didn't look like jobs, so the user can review them
+	`CREATE TABLE hnrejects (
+	id INTEGER NOT NULL PRIMARY KEY,
+	parent INTEGER NOT NULL,
+	text TEXT NOT NULL,
+	time INTEGER NOT NULL,
+	fetched_time INTEGER NOT NULL,
+	reason TEXT NOT NULL
+	);`,
+	// 9: every version of each job's text, starting with the one we have
+	`CREATE TABLE job_revisions (
+	job_id INTEGER NOT NULL,
+	fetched_time INTEGER NOT NULL,
+	text TEXT NOT NULL
+	);
+	CREATE INDEX job_revisions_job_id ON job_revisions (job_id);
+	INSERT INTO job_revisions (job_id, fetched_time, text) SELECT id, fetched_time, text FROM hnjobs;`,
+	// 10: which scoring rules matched each job
+	`CREATE TABLE job_rule_hits (
+	job_id INTEGER NOT NULL,
+	rule_index INTEGER NOT NULL,
+	rule TEXT NOT NULL,
+	points INTEGER NOT NULL,
+	matches TEXT,
+	PRIMARY KEY (job_id, rule_index)
+	);`,
+}
+
 func NewDB(filepath string) error {
-	err := OpenDB(filepath)
+	var err error
+	store.db, err = sql.Open("sqlite", "file:"+filepath)
 	if err != nil {
-		return err
+		return fmt.Errorf("error opening DB: %v", err)
 	}
+	store.jobUpsert = nil // prepared on the previous DB, if any
 	_, err = store.db.Exec(newDBSchema)
 	if err != nil {
 		return err
 	}
-	return nil
+	return migrate()
 }
 
-// TODO schema migrations
+func migrate() error {
+	var version int
+	err := store.db.QueryRow("PRAGMA user_version").Scan(&version)
+	if err != nil {
+		return fmt.Errorf("error reading schema version: %v", err)
+	}
+	store.writeMutex.Lock()
+	defer store.writeMutex.Unlock()
+	for ; version < len(migrations); version++ {
+		tx, err := store.db.Begin()
+		if err != nil {
+			return fmt.Errorf("error starting migration %d: %v", version+1, err)
+		}
+		_, err = tx.Exec(migrations[version])
+		if err == nil {
+			_, err = tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", version+1))
+		}
+		if err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("error applying migration %d: %v", version+1, err)
+		}
+		err = tx.Commit()
+		if err != nil {
+			return fmt.Errorf("error committing migration %d: %v", version+1, err)
+		}
+	}
+	return nil
+}
@@ -1,3 +1,4 @@
+-- Version 0 of the schema.  Later changes are in the migrations in management.go
 CREATE TABLE "hnjobs" (
     "id"	INTEGER NOT NULL UNIQUE,
     "parent"	INTEGER NOT NULL,
@@ -0,0 +1,162 @@
+// Package gazetteer is a small offline database of the cities and metro areas which show up in job postings, so that
+// we can tell that "SF Bay Area / NYC / London" is three places and how far each of them is from you.
+package gazetteer
+
+import (
+	_ "embed"
+	"fmt"
+	"html"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+//go:embed places.tsv
+var placesTSV string
+
+type Place struct {
+	Key     string // stable ID, e.g. "sf-bay-area"
+	Name    string
+	Country string // ISO 3166 alpha-2
+	Lat     float64
+	Lon     float64
+}
+
+var places map[string]*Place
+var aliasToKey map[string]string // lowercase alias -> key
+var placeRegex *regexp.Regexp
+var loadOnce sync.Once
+
+func load() {
+	places = make(map[string]*Place)
+	aliasToKey = make(map[string]string)
+	var caseInsensitive, caseSensitive []string
+	for i, line := range strings.Split(placesTSV, "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) < 5 {
+			panic(fmt.Errorf("places.tsv line %d: not enough fields", i+1))
+		}
+		p := &Place{Key: fields[0], Name: fields[1], Country: fields[2]}
+		var err error
+		p.Lat, err = strconv.ParseFloat(fields[3], 64)
+		if err == nil {
+			p.Lon, err = strconv.ParseFloat(fields[4], 64)
+		}
+		if err != nil {
+			panic(fmt.Errorf("places.tsv line %d: %v", i+1, err))
+		}
+		places[p.Key] = p
+		aliases := []string{p.Name}
+		if len(fields) > 5 && fields[5] != "" {
+			aliases = append(aliases, strings.Split(fields[5], ",")...)
+		}
+		for _, a := range aliases {
+			aliasToKey[strings.ToLower(a)] = p.Key
+			if strings.ToLower(a) == a || a == p.Name {
+				caseInsensitive = append(caseInsensitive, regexp.QuoteMeta(a))
+			} else {
+				// e.g. "LA", which we don't want to find in "la la land"
+				caseSensitive = append(caseSensitive, regexp.QuoteMeta(a))
+			}
+		}
+	}
+	// Longest first, so that we find "New York City" rather than "New York"
+	byLen := func(a, b string) int { return len(b) - len(a) }
+	slices.SortFunc(caseInsensitive, byLen)
+	slices.SortFunc(caseSensitive, byLen)
+	placeRegex = regexp.MustCompile(
+		`\b(?:(?i:` + strings.Join(caseInsensitive, "|") + `)|(?:` + strings.Join(caseSensitive, "|") + `))`,
+	)
+}
+
+// Get returns the place with the given key, or nil
+func Get(key string) *Place {
+	loadOnce.Do(load)
+	return places[key]
+}
+
+// Lookup finds a place by name or alias, e.g. "Bay Area", or nil
+func Lookup(name string) *Place {
+	loadOnce.Do(load)
+	key, ok := aliasToKey[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return nil
+	}
+	return places[key]
+}
+
+// Find returns all the places mentioned in s, in order, without duplicates
+func Find(s string) []*Place {
+	loadOnce.Do(load)
+	var found []*Place
+	for _, loc := range placeRegex.FindAllStringIndex(s, -1) {
+		// \b doesn't work after e.g. "Bogotá" or "D.C.", so check the end ourselves
+		if next, _ := utf8.DecodeRuneInString(s[loc[1]:]); unicode.IsLetter(next) || unicode.IsDigit(next) {
+			continue
+		}
+		p := places[aliasToKey[strings.ToLower(s[loc[0]:loc[1]])]]
+		if p != nil && !slices.Contains(found, p) {
+			found = append(found, p)
+		}
+	}
+	return found
+}
+
+var locationLineRegex = regexp.MustCompile(`(?im)\blocations?:\s*([^\n<]+)`)
+
+// Locate returns the keys of the places where a job is, looking at the header's location fields first and then any
+// "Location: ..." lines in text (which is HTML).
+func Locate(text string, headerFields ...string) []string {
+	var keys []string
+	add := func(s string) {
+		for _, p := range Find(s) {
+			if !slices.Contains(keys, p.Key) {
+				keys = append(keys, p.Key)
+			}
+		}
+	}
+	for _, f := range headerFields {
+		add(f)
+	}
+	for _, m := range locationLineRegex.FindAllStringSubmatch(text, -1) {
+		add(html.UnescapeString(m[1]))
+	}
+	return keys
+}
+
+// ParseLatLon parses "52.52,13.40"
+func ParseLatLon(s string) (float64, float64, bool) {
+	latStr, lonStr, ok := strings.Cut(s, ",")
+	if !ok {
+		return 0, 0, false
+	}
+	lat, err := strconv.ParseFloat(strings.TrimSpace(latStr), 64)
+	if err != nil || lat < -90 || lat > 90 {
+		return 0, 0, false
+	}
+	lon, err := strconv.ParseFloat(strings.TrimSpace(lonStr), 64)
+	if err != nil || lon < -180 || lon > 180 {
+		return 0, 0, false
+	}
+	return lat, lon, true
+}
+
+const earthRadiusKm = 6371
+
+// DistanceKm is the great-circle distance between two points
+func DistanceKm(lat1, lon1, lat2, lon2 float64) float64 {
+	toRad := func(d float64) float64 { return d * math.Pi / 180 }
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
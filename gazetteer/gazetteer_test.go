@@ -0,0 +1,71 @@
+package gazetteer
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestData(t *testing.T) {
+	loadOnce.Do(load) // panics on bad data
+	if len(places) < 100 {
+		t.Errorf("expected lots of places, got %d", len(places))
+	}
+}
+
+func TestFind(t *testing.T) {
+	tests := map[string][]string{
+		"SF Bay Area / NYC / London": {"sf-bay-area", "nyc", "london"},
+		"New York City or Remote":    {"nyc"},
+		"München, Germany":           {"munich"},
+		"la la land":                 nil,
+		"LA or San Francisco":        {"los-angeles", "sf-bay-area"},
+		"Remote (US)":                nil,
+		"Washington D.C. / Bogotá":   {"washington-dc", "bogota"},
+		"Berliner Straße":            nil,
+	}
+	for s, want := range tests {
+		t.Run(s, func(t *testing.T) {
+			var got []string
+			for _, p := range Find(s) {
+				got = append(got, p.Key)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestLocate(t *testing.T) {
+	got := Locate("Acme | SRE | Berlin<p>Location: Berlin / Amsterdam<p>Hamburg is nice", "Berlin", "Hybrid")
+	want := []string{"berlin", "amsterdam"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	if p := Lookup("bay area"); p == nil || p.Key != "sf-bay-area" {
+		t.Errorf("expected sf-bay-area, got %v", p)
+	}
+	if p := Lookup("Atlantis"); p != nil {
+		t.Errorf("expected nil, got %v", p)
+	}
+}
+
+func TestDistance(t *testing.T) {
+	berlin := Get("berlin")
+	munich := Get("munich")
+	d := DistanceKm(berlin.Lat, berlin.Lon, munich.Lat, munich.Lon)
+	if math.Abs(d-504) > 10 {
+		t.Errorf("Berlin to Munich should be about 504km, got %f", d)
+	}
+	lat, lon, ok := ParseLatLon("52.52, 13.40")
+	if !ok || lat != 52.52 || lon != 13.40 {
+		t.Errorf("bad ParseLatLon: %f %f %v", lat, lon, ok)
+	}
+	if _, _, ok = ParseLatLon("north pole"); ok {
+		t.Error("expected ParseLatLon to fail")
+	}
+}
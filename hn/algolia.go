@@ -0,0 +1,145 @@
+package hn
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultAlgoliaBaseURL = "https://hn.algolia.com/api/v1"
+
+var algoliaBaseURL = defaultAlgoliaBaseURL // to facilitate testing
+
+// https://hn.algolia.com/api/v1/items/41709301
+type algoliaItem struct {
+	Id         int            `json:"id"`
+	CreatedAtI int64          `json:"created_at_i"`
+	Type       string         `json:"type"`
+	Author     *string        `json:"author"`
+	Title      *string        `json:"title"`
+	Text       *string        `json:"text"`
+	ParentId   *int           `json:"parent_id"`
+	Children   []*algoliaItem `json:"children"`
+}
+
+// asItem converts to the firebase-style Item.  Algolia doesn't tell us about deleted items, but they come back with
+// neither an author nor text.
+func (ai *algoliaItem) asItem(fetchedGoTime time.Time) *Item {
+	i := &Item{
+		Id:            ai.Id,
+		Type:          ai.Type,
+		Time:          ai.CreatedAtI,
+		FetchedTime:   fetchedGoTime.Unix(),
+		FetchedGoTime: fetchedGoTime,
+	}
+	if ai.Author != nil {
+		i.By = *ai.Author
+	}
+	if ai.Title != nil {
+		i.Title = *ai.Title
+	}
+	if ai.Text != nil {
+		i.Text = normalizeAlgoliaText(*ai.Text)
+	}
+	if ai.ParentId != nil {
+		i.Parent = *ai.ParentId
+	}
+	if ai.Author == nil && ai.Text == nil {
+		i.Deleted = true
+	}
+	for _, child := range ai.Children {
+		i.Kids = append(i.Kids, child.Id)
+	}
+	return i
+}
+
+// normalizeAlgoliaText makes Algolia's HTML look like firebase's, where paragraphs are separated by a bare <p>, so
+// that switching backends doesn't make every job look edited.
+func normalizeAlgoliaText(s string) string {
+	s = strings.ReplaceAll(s, "</p>", "")
+	s = strings.TrimPrefix(s, "<p>")
+	return s
+}
+
+func fetchAlgoliaItem(ctx context.Context, id int) (*algoliaItem, error) {
+	resp, err := fetchURL(ctx, algoliaBaseURL+"/items/"+strconv.Itoa(id))
+	if err != nil {
+		return nil, fmt.Errorf("can't fetch: %w", err)
+	}
+	var ai algoliaItem
+	err = json.Unmarshal(resp, &ai)
+	if err != nil {
+		return nil, fmt.Errorf("can't unmarshal: %v", err)
+	}
+	if ai.Id == 0 {
+		return nil, fmt.Errorf("%w: id %d", ErrItemNotFound, id)
+	}
+	return &ai, nil
+}
+
+// FetchAlgoliaThread fetches a story and all of its top-level comments in a single request.
+func FetchAlgoliaThread(ctx context.Context, id int) (*Story, []*Comment, error) {
+	ai, err := fetchAlgoliaItem(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	story, err := ai.asItem(now).AsStory()
+	if err != nil {
+		return nil, nil, err
+	}
+	var comments []*Comment
+	for _, child := range ai.Children {
+		c, err := child.asItem(now).AsComment()
+		if err != nil {
+			return nil, nil, err
+		}
+		comments = append(comments, c)
+	}
+	return story, comments, nil
+}
+
+// AlgoliaBackend uses the Algolia HN Search API, which can return a whole thread in one request.  Fetching a story
+// caches its top-level comments so that the following FetchComment calls don't touch the network.
+type AlgoliaBackend struct {
+	mu       sync.Mutex
+	comments map[int]*Comment
+}
+
+func NewAlgoliaBackend() *AlgoliaBackend {
+	return &AlgoliaBackend{comments: make(map[int]*Comment)}
+}
+
+func (ab *AlgoliaBackend) FetchStory(ctx context.Context, id int) (*Story, error) {
+	story, comments, err := FetchAlgoliaThread(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	ab.mu.Lock()
+	for _, c := range comments {
+		ab.comments[c.Id] = c
+	}
+	ab.mu.Unlock()
+	return story, nil
+}
+
+func (ab *AlgoliaBackend) FetchComment(ctx context.Context, id int) (*Comment, error) {
+	ab.mu.Lock()
+	c, ok := ab.comments[id]
+	if ok {
+		delete(ab.comments, id) // each comment is normally only requested once
+	}
+	ab.mu.Unlock()
+	if ok {
+		return c, nil
+	}
+	ai, err := fetchAlgoliaItem(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return ai.asItem(time.Now()).AsComment()
+}
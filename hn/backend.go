@@ -0,0 +1,40 @@
+package hn
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	BackendFirebase = "firebase"
+	BackendAlgolia  = "algolia"
+)
+
+// Backend is a source of stories and comments.  All backends return the same Story / Comment types.
+type Backend interface {
+	FetchStory(ctx context.Context, id int) (*Story, error)
+	FetchComment(ctx context.Context, id int) (*Comment, error)
+}
+
+// NewBackend returns the backend with the given name.  An empty name is the default (firebase).
+func NewBackend(name string) (Backend, error) {
+	switch name {
+	case "", BackendFirebase:
+		return &FirebaseBackend{}, nil
+	case BackendAlgolia:
+		return NewAlgoliaBackend(), nil
+	default:
+		return nil, fmt.Errorf("unknown backend %q", name)
+	}
+}
+
+// FirebaseBackend is the official HN API, which requires one request per item.
+type FirebaseBackend struct{}
+
+func (fb *FirebaseBackend) FetchStory(ctx context.Context, id int) (*Story, error) {
+	return FetchStory(ctx, id)
+}
+
+func (fb *FirebaseBackend) FetchComment(ctx context.Context, id int) (*Comment, error) {
+	return FetchComment(ctx, id)
+}
@@ -0,0 +1,186 @@
+package hn
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+)
+
+type CassetteMode int
+
+const (
+	CassetteOff CassetteMode = iota
+	CassetteRecord
+	CassetteReplay
+)
+
+var ErrNotInCassette = errors.New("request not found in cassette")
+
+// interaction is one recorded response.  A cassette file is JSON lines of these, in the order they happened.
+type interaction struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"status"`
+	RetryAfter string `json:"retry_after,omitempty"`
+	Body       string `json:"body"`
+}
+
+// cassette is an http.RoundTripper which either records every response to a file or replays them from one.  When
+// replaying, each URL's responses are returned in the order they were recorded, and the last one repeats forever.
+type cassette struct {
+	mode   CassetteMode
+	mu     sync.Mutex
+	file   *os.File                  // record
+	inner  http.RoundTripper         // record
+	byURL  map[string][]*interaction // replay
+	replay map[string]int            // replay: how many times we've served each URL
+}
+
+// curCassette is the cassette in use, if any.  Configure() keeps using it.
+var curCassette *cassette
+
+// UseCassette makes all requests from this package record to / replay from the cassette file at path.  Replay mode
+// never touches the network, so requests skip the rate limit while replaying.
+func UseCassette(path string, mode CassetteMode) error {
+	c := &cassette{mode: mode}
+	switch mode {
+	case CassetteOff:
+		curCassette = nil
+		httpClient = baseClient
+		return nil
+	case CassetteRecord:
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			return fmt.Errorf("can't create cassette: %v", err)
+		}
+		c.file = f
+		c.inner = baseClient.Transport
+		if c.inner == nil {
+			c.inner = http.DefaultTransport
+		}
+	case CassetteReplay:
+		err := c.load(path)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unhandled cassette mode %d", mode)
+	}
+	curCassette = c
+	httpClient = &http.Client{Timeout: baseClient.Timeout, Transport: c}
+	return nil
+}
+
+// replaying is whether requests are being served from a cassette instead of the network
+func replaying() bool {
+	return curCassette != nil && curCassette.mode == CassetteReplay
+}
+
+// setInner makes a recording cassette use a new transport, e.g. after Configure()
+func (c *cassette) setInner(rt http.RoundTripper) {
+	c.mu.Lock()
+	c.inner = rt
+	c.mu.Unlock()
+}
+
+func (c *cassette) load(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("can't open cassette: %v", err)
+	}
+	defer f.Close()
+	c.byURL = make(map[string][]*interaction)
+	c.replay = make(map[string]int)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 64*1024*1024) // algolia threads are big
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var i interaction
+		err = json.Unmarshal(scanner.Bytes(), &i)
+		if err != nil {
+			return fmt.Errorf("can't parse cassette line %d: %v", line, err)
+		}
+		c.byURL[i.URL] = append(c.byURL[i.URL], &i)
+	}
+	if err = scanner.Err(); err != nil {
+		return fmt.Errorf("can't read cassette: %v", err)
+	}
+	return nil
+}
+
+func (c *cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	url := req.URL.String()
+	if c.mode == CassetteReplay {
+		c.mu.Lock()
+		recorded, ok := c.byURL[url]
+		n := c.replay[url]
+		c.replay[url]++
+		c.mu.Unlock()
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrNotInCassette, url)
+		}
+		if n >= len(recorded) {
+			n = len(recorded) - 1
+		}
+		return recorded[n].response(req), nil
+	}
+
+	c.mu.Lock()
+	inner := c.inner
+	c.mu.Unlock()
+	resp, err := inner.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	i := &interaction{
+		URL:        url,
+		StatusCode: resp.StatusCode,
+		RetryAfter: resp.Header.Get("Retry-After"),
+		Body:       string(body),
+	}
+	line, err := json.Marshal(i)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	_, err = c.file.Write(append(line, '\n'))
+	c.mu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("can't write to cassette: %v", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+func (i *interaction) response(req *http.Request) *http.Response {
+	resp := &http.Response{
+		Status:        strconv.Itoa(i.StatusCode) + " " + http.StatusText(i.StatusCode),
+		StatusCode:    i.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        make(http.Header),
+		Body:          io.NopCloser(bytes.NewReader([]byte(i.Body))),
+		ContentLength: int64(len(i.Body)),
+		Request:       req,
+	}
+	if i.RetryAfter != "" {
+		resp.Header.Set("Retry-After", i.RetryAfter)
+	}
+	return resp
+}
@@ -1,24 +1,196 @@
 package hn
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand/v2"
+	"net"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 )
 
-var baseURL = "https://hacker-news.firebaseio.com/v0" // to facilitate testing
+const defaultBaseURL = "https://hacker-news.firebaseio.com/v0"
 
-func fetch(ctx context.Context, url string) ([]byte, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", baseURL+url, nil)
+var baseURL = defaultBaseURL // to facilitate testing
+
+// baseClient is built by Configure(), and httpClient is what we actually use (it's different while using a cassette)
+var baseClient = &http.Client{Timeout: defaultTimeout}
+var httpClient = baseClient
+var userAgent = defaultUserAgent
+
+const defaultTimeout = 30 * time.Second
+const defaultUserAgent = "hnjobs (+https://github.com/mwinters0/hnjobs)"
+
+// ClientOptions configures the HTTP client used for all requests.  Zero values mean "use the default".
+type ClientOptions struct {
+	Timeout        time.Duration
+	UserAgent      string
+	ProxyURL       string // otherwise $HTTPS_PROXY etc. are respected
+	MaxIdleConns   int
+	BaseURL        string  // firebase API, e.g. an internal mirror
+	AlgoliaBaseURL string  // algolia API, for the algolia backend
+	RatePerSec     float64 // shared by all requests; < 0 for no limit
+	Burst          int
+}
+
+// Configure (re)builds the HTTP client.  Call it before fetching anything.
+func Configure(opts ClientOptions) error {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if opts.ProxyURL != "" {
+		proxy, err := url.Parse(opts.ProxyURL)
+		if err != nil {
+			return fmt.Errorf("invalid proxy URL %q: %v", opts.ProxyURL, err)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+	if opts.MaxIdleConns > 0 {
+		transport.MaxIdleConns = opts.MaxIdleConns
+		transport.MaxIdleConnsPerHost = opts.MaxIdleConns // we only talk to one or two hosts
+	}
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+	newBaseURL, err := apiURL(opts.BaseURL, defaultBaseURL)
+	if err != nil {
+		return err
+	}
+	newAlgoliaBaseURL, err := apiURL(opts.AlgoliaBaseURL, defaultAlgoliaBaseURL)
+	if err != nil {
+		return err
+	}
+	baseURL, algoliaBaseURL = newBaseURL, newAlgoliaBaseURL
+	userAgent = defaultUserAgent
+	if opts.UserAgent != "" {
+		userAgent = opts.UserAgent
+	}
+	baseClient = &http.Client{Timeout: timeout, Transport: transport}
+	httpClient = baseClient
+	if curCassette != nil {
+		// keep recording / replaying
+		curCassette.setInner(transport)
+		httpClient = &http.Client{Timeout: timeout, Transport: curCassette}
+	}
+	SetRateLimit(cmp.Or(opts.RatePerSec, defaultRatePerSec), cmp.Or(opts.Burst, defaultBurst))
+	return nil
+}
+
+// apiURL validates an API base URL from the options, or returns the default if it's empty
+func apiURL(s string, defaultURL string) (string, error) {
+	if s == "" {
+		return defaultURL, nil
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("invalid base URL %q", s)
+	}
+	return strings.TrimSuffix(s, "/"), nil
+}
+
+var ErrItemNotFound = errors.New("item not found")
+var ErrWrongItemType = errors.New("wrong item type")
+
+// StatusError is returned when the API responds with a non-2xx HTTP status.
+type StatusError struct {
+	StatusCode int
+	retryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected HTTP status %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// IsTemporary reports whether err is the kind of error that might go away if we try again later: timeouts, 429s and
+// 5xx responses.
+func IsTemporary(err error) bool {
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.StatusCode == http.StatusTooManyRequests || se.StatusCode >= 500
+	}
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return true
+	}
+	return false
+}
+
+// retryPolicy controls how fetch() retries temporary errors.  Delays grow exponentially from baseDelay and are
+// jittered so that our workers don't all retry in lockstep.
+var retryPolicy = struct {
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+}{
+	maxAttempts: 5,
+	baseDelay:   500 * time.Millisecond,
+	maxDelay:    15 * time.Second,
+}
+
+func backoffDelay(attempt int) time.Duration {
+	d := retryPolicy.baseDelay << attempt
+	if d > retryPolicy.maxDelay || d <= 0 {
+		d = retryPolicy.maxDelay
+	}
+	// "equal jitter": somewhere between d/2 and d
+	half := d / 2
+	return half + rand.N(half+1)
+}
+
+// fetch GETs a path from the firebase API
+func fetch(ctx context.Context, path string) ([]byte, error) {
+	return fetchURL(ctx, baseURL+path)
+}
+
+// fetchURL GETs a URL, retrying temporary errors
+func fetchURL(ctx context.Context, url string) ([]byte, error) {
+	var err error
+	var body []byte
+	for attempt := 0; attempt < retryPolicy.maxAttempts; attempt++ {
+		if attempt > 0 {
+			delay := backoffDelay(attempt - 1)
+			var se *StatusError
+			if errors.As(err, &se) && se.retryAfter > delay {
+				delay = se.retryAfter
+			}
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, ctx.Err()
+			case <-timer.C:
+			}
+		}
+		body, err = fetchOnce(ctx, url)
+		if err == nil {
+			return body, nil
+		}
+		if ctx.Err() != nil || !IsTemporary(err) {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("giving up after %d attempts: %w", retryPolicy.maxAttempts, err)
+}
+
+func fetchOnce(ctx context.Context, url string) ([]byte, error) {
+	if !replaying() {
+		err := limiter.Wait(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
-	client := http.DefaultClient
-	resp, err := client.Do(req)
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := httpClient.Do(req)
 	defer func() {
 		if resp != nil {
 			resp.Body.Close()
@@ -27,6 +199,13 @@ func fetch(ctx context.Context, url string) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		se := &StatusError{StatusCode: resp.StatusCode}
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			se.retryAfter = time.Duration(secs) * time.Second
+		}
+		return nil, se
+	}
 	var bodyBytes []byte
 	bodyBytes, err = io.ReadAll(resp.Body)
 	if err != nil {
@@ -35,13 +214,11 @@ func fetch(ctx context.Context, url string) ([]byte, error) {
 	return bodyBytes, nil
 }
 
-// TODO: Everything in the HN API is an "item". We should fetch items and validate type before converting to Comment / etc.
-
 // https://hacker-news.firebaseio.com/v0/user/whoishiring/submitted.json
 func FetchSubmissions(ctx context.Context, user string) ([]int, error) {
 	resp, err := fetch(ctx, "/user/"+user+"/submitted.json")
 	if err != nil {
-		return nil, fmt.Errorf("can't fetch: %v", err)
+		return nil, fmt.Errorf("can't fetch: %w", err)
 	}
 
 	var i []int
@@ -52,6 +229,80 @@ func FetchSubmissions(ctx context.Context, user string) ([]int, error) {
 	return i, nil
 }
 
+// Updates are the items and profiles which changed recently (the last few minutes.)
+type Updates struct {
+	Items    []int
+	Profiles []string
+}
+
+// https://hacker-news.firebaseio.com/v0/updates.json
+func FetchUpdates(ctx context.Context) (*Updates, error) {
+	resp, err := fetch(ctx, "/updates.json")
+	if err != nil {
+		return nil, fmt.Errorf("can't fetch: %w", err)
+	}
+	var u Updates
+	err = json.Unmarshal(resp, &u)
+	if err != nil {
+		return nil, fmt.Errorf("can't unmarshal: %v", err)
+	}
+	return &u, nil
+}
+
+type ItemType = string
+
+const (
+	ItemTypeStory   ItemType = "story"
+	ItemTypeComment ItemType = "comment"
+	ItemTypeJob     ItemType = "job"
+	ItemTypePoll    ItemType = "poll"
+	ItemTypePollOpt ItemType = "pollopt"
+)
+
+// Item is the raw form of everything in the HN API.  Fetch one of these and then convert it with AsStory() /
+// AsComment(), which validate the type.
+type Item struct {
+	Id            int
+	Type          ItemType
+	By            string
+	Deleted       bool
+	Dead          bool
+	Kids          []int
+	Parent        int
+	Title         string
+	Text          string
+	Time          int64
+	FetchedTime   int64     `json:"-"`
+	FetchedGoTime time.Time `json:"-"`
+}
+
+// https://hacker-news.firebaseio.com/v0/item/41709301.json
+func FetchItem(ctx context.Context, id int) (*Item, error) {
+	var i Item
+	resp, err := fetch(ctx, "/item/"+strconv.Itoa(id)+".json")
+	if err != nil {
+		return nil, fmt.Errorf("can't fetch: %w", err)
+	}
+	err = json.Unmarshal(resp, &i)
+	if err != nil {
+		return nil, fmt.Errorf("can't unmarshal: %v", err)
+	}
+	if i.Id == 0 {
+		// the API returns `null` for IDs which don't exist
+		return nil, fmt.Errorf("%w: id %d", ErrItemNotFound, id)
+	}
+	i.FetchedGoTime = time.Now()
+	i.FetchedTime = i.FetchedGoTime.Unix()
+	return &i, nil
+}
+
+func (i *Item) checkType(want ItemType) error {
+	if i.Type != want {
+		return fmt.Errorf("%w: item %d is a %q, not a %q", ErrWrongItemType, i.Id, i.Type, want)
+	}
+	return nil
+}
+
 // https://hacker-news.firebaseio.com/v0/item/41709301.json
 type Story struct {
 	Id            int
@@ -61,22 +312,31 @@ type Story struct {
 	Title         string
 	FetchedTime   int64     `json:"-"`
 	FetchedGoTime time.Time `json:"-"`
+	Source        string    // not from the API: the name of the app.Source which found this story
 }
 
-func FetchStory(ctx context.Context, id int) (*Story, error) {
-	var s Story
-	resp, err := fetch(ctx, "/item/"+strconv.Itoa(id)+".json")
-	if err != nil {
-		return &s, fmt.Errorf("can't fetch: %v", err)
+// AsStory converts the Item to a Story, returning ErrWrongItemType if the item is anything else.
+func (i *Item) AsStory() (*Story, error) {
+	if err := i.checkType(ItemTypeStory); err != nil {
+		return nil, err
 	}
-	err = json.Unmarshal(resp, &s)
+	return &Story{
+		Id:            i.Id,
+		Kids:          i.Kids,
+		Time:          i.Time,
+		GoTime:        time.Unix(i.Time, 0),
+		Title:         i.Title,
+		FetchedTime:   i.FetchedTime,
+		FetchedGoTime: i.FetchedGoTime,
+	}, nil
+}
+
+func FetchStory(ctx context.Context, id int) (*Story, error) {
+	i, err := FetchItem(ctx, id)
 	if err != nil {
-		return &s, fmt.Errorf("can't unmarshal: %v", err)
+		return nil, err
 	}
-	s.FetchedGoTime = time.Now()
-	s.FetchedTime = s.FetchedGoTime.Unix()
-	s.GoTime = time.Unix(s.Time, 0)
-	return &s, nil
+	return i.AsStory()
 }
 
 // https://hacker-news.firebaseio.com/v0/item/41733646.json
@@ -88,21 +348,32 @@ type Comment struct {
 	GoTime        time.Time `json:"-"`
 	FetchedTime   int64     `json:"-"`
 	FetchedGoTime time.Time `json:"-"`
+	Deleted       bool
+	Dead          bool
 }
 
-func FetchComment(ctx context.Context, id int) (*Comment, error) {
-	var c Comment
-	resp, err := fetch(ctx, "/item/"+strconv.Itoa(id)+".json")
-	if err != nil {
-		return nil, fmt.Errorf("can't fetch: %v", err)
+// AsComment converts the Item to a Comment, returning ErrWrongItemType if the item is anything else.
+func (i *Item) AsComment() (*Comment, error) {
+	if err := i.checkType(ItemTypeComment); err != nil {
+		return nil, err
 	}
-	err = json.Unmarshal(resp, &c)
+	return &Comment{
+		Id:            i.Id,
+		Parent:        i.Parent,
+		Text:          i.Text,
+		Time:          i.Time,
+		GoTime:        time.Unix(i.Time, 0),
+		FetchedTime:   i.FetchedTime,
+		FetchedGoTime: i.FetchedGoTime,
+		Deleted:       i.Deleted,
+		Dead:          i.Dead,
+	}, nil
+}
+
+func FetchComment(ctx context.Context, id int) (*Comment, error) {
+	i, err := FetchItem(ctx, id)
 	if err != nil {
-		return nil, fmt.Errorf("can't unmarshal: %v", err)
+		return nil, err
 	}
-	c.GoTime = time.Unix(c.Time, 0)
-	now := time.Now()
-	c.FetchedTime = now.Unix()
-	c.FetchedGoTime = now
-	return &c, nil
+	return i.AsComment()
 }
@@ -2,8 +2,10 @@ package hn
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
@@ -32,6 +34,31 @@ func TestFetch(t *testing.T) {
 		}
 	})
 
+	t.Run("Updates", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			expectedURL := "/updates.json"
+			if r.URL.Path != expectedURL {
+				t.Errorf("Expected to request '%s', got: %s", expectedURL, r.URL.Path)
+			}
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{"items": [8423305, 8420805], "profiles": ["thefox", "mdda"]}`))
+		}))
+		defer server.Close()
+
+		baseURL = server.URL
+		actual, err := FetchUpdates(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := &Updates{
+			Items:    []int{8423305, 8420805},
+			Profiles: []string{"thefox", "mdda"},
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("Expected:\n  %v\ngot:\n  %v", expected, actual)
+		}
+	})
+
 	t.Run("Story", func(t *testing.T) {
 		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			expectedURL := "/item/42.json"
@@ -108,4 +135,459 @@ func TestFetch(t *testing.T) {
 			t.Errorf("Expected:\n  %#v\ngot:\n  %#v", expected, actual)
 		}
 	})
+
+	t.Run("DeletedComment", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{"deleted": true, "id": 79, "parent": 999, "time": 1727794816, "type": "comment"}`))
+		}))
+		defer server.Close()
+
+		baseURL = server.URL
+		actual, err := FetchComment(context.Background(), 79)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !actual.Deleted || actual.Dead || actual.Text != "" {
+			t.Errorf("Expected an empty deleted comment, got:\n  %#v", actual)
+		}
+	})
+
+	t.Run("Item", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{
+  "by": "bro",
+  "dead": true,
+  "id": 78,
+  "kids": [80, 81],
+  "parent": 999,
+  "text": "badjob",
+  "time": 1727794816,
+  "type": "comment"
+}`))
+		}))
+		defer server.Close()
+
+		baseURL = server.URL
+		actual, err := FetchItem(context.Background(), 78)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := &Item{
+			Id:            78,
+			Type:          ItemTypeComment,
+			By:            "bro",
+			Dead:          true,
+			Kids:          []int{80, 81},
+			Parent:        999,
+			Text:          "badjob",
+			Time:          1727794816,
+			FetchedTime:   actual.FetchedTime, // no good way to test these
+			FetchedGoTime: actual.FetchedGoTime,
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("Expected:\n  %#v\ngot:\n  %#v", expected, actual)
+		}
+	})
+
+	t.Run("WrongType", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			switch r.URL.Path {
+			case "/item/77.json":
+				_, _ = w.Write([]byte(`{"by": "bro", "id": 77, "parent": 999, "text": "goodjob", "time": 1727794816, "type": "comment"}`))
+			case "/item/88.json":
+				_, _ = w.Write([]byte(`{"by": "pollster", "id": 88, "kids": [1], "parts": [2, 3], "time": 1727794816, "title": "Tabs?", "type": "poll"}`))
+			default:
+				t.Errorf("Unexpected request: %s", r.URL.Path)
+			}
+		}))
+		defer server.Close()
+
+		baseURL = server.URL
+		_, err := FetchStory(context.Background(), 77)
+		if !errors.Is(err, ErrWrongItemType) {
+			t.Errorf("Expected ErrWrongItemType for a comment fetched as a story, got: %v", err)
+		}
+		_, err = FetchStory(context.Background(), 88)
+		if !errors.Is(err, ErrWrongItemType) {
+			t.Errorf("Expected ErrWrongItemType for a poll fetched as a story, got: %v", err)
+		}
+		_, err = FetchComment(context.Background(), 88)
+		if !errors.Is(err, ErrWrongItemType) {
+			t.Errorf("Expected ErrWrongItemType for a poll fetched as a comment, got: %v", err)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`null`))
+		}))
+		defer server.Close()
+
+		baseURL = server.URL
+		_, err := FetchItem(context.Background(), 123456789)
+		if !errors.Is(err, ErrItemNotFound) {
+			t.Errorf("Expected ErrItemNotFound, got: %v", err)
+		}
+	})
+}
+
+func TestConfigure(t *testing.T) {
+	defer func() {
+		_ = Configure(ClientOptions{})
+	}()
+	var gotUA string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUA = r.Header.Get("User-Agent")
+		if r.URL.Path != "/mirror/v0/user/foouser/submitted.json" {
+			t.Errorf("Unexpected request: %s", r.URL.Path)
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`[1]`))
+	}))
+	defer server.Close()
+
+	err := Configure(ClientOptions{
+		Timeout:      time.Second,
+		UserAgent:    "test-agent",
+		MaxIdleConns: 2,
+		BaseURL:      server.URL + "/mirror/v0/",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = FetchSubmissions(context.Background(), "foouser")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotUA != "test-agent" {
+		t.Errorf("Expected User-Agent 'test-agent', got '%s'", gotUA)
+	}
+
+	err = Configure(ClientOptions{RatePerSec: 2, Burst: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limiter.rate != 2 || limiter.burst != 3 {
+		t.Errorf("Expected a rate limit of 2/s with a burst of 3, got %v/s with %v", limiter.rate, limiter.burst)
+	}
+	err = Configure(ClientOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limiter.rate != defaultRatePerSec || limiter.burst != defaultBurst {
+		t.Errorf("Expected the default rate limit, got %v/s with a burst of %v", limiter.rate, limiter.burst)
+	}
+	if baseURL != defaultBaseURL {
+		t.Errorf("Expected the default base URL once it's no longer configured, got %s", baseURL)
+	}
+
+	err = Configure(ClientOptions{AlgoliaBaseURL: "https://algolia.example.com/api/v1/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if algoliaBaseURL != "https://algolia.example.com/api/v1" {
+		t.Errorf("Expected the configured algolia URL, got %s", algoliaBaseURL)
+	}
+	err = Configure(ClientOptions{AlgoliaBaseURL: "not a url"})
+	if err == nil {
+		t.Errorf("Expected an error for an invalid algolia URL")
+	}
+
+	err = Configure(ClientOptions{ProxyURL: "://nope"})
+	if err == nil {
+		t.Errorf("Expected an error for an invalid proxy URL")
+	}
+	err = Configure(ClientOptions{BaseURL: "not a url"})
+	if err == nil {
+		t.Errorf("Expected an error for an invalid base URL")
+	}
+}
+
+func TestRetry(t *testing.T) {
+	oldPolicy := retryPolicy
+	t.Cleanup(func() {
+		retryPolicy = oldPolicy
+	})
+	retryPolicy.baseDelay = time.Millisecond
+	retryPolicy.maxDelay = 5 * time.Millisecond
+
+	t.Run("TemporaryErrors", func(t *testing.T) {
+		calls := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			switch calls {
+			case 1:
+				w.WriteHeader(http.StatusServiceUnavailable)
+			case 2:
+				w.WriteHeader(http.StatusTooManyRequests)
+			default:
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte(`[1,2,5]`))
+			}
+		}))
+		defer server.Close()
+
+		baseURL = server.URL
+		actual, err := FetchSubmissions(context.Background(), "foouser")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if calls != 3 {
+			t.Errorf("Expected 3 requests, got %d", calls)
+		}
+		expected := []int{1, 2, 5}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("Expected:\n  %v\ngot:\n  %v", expected, actual)
+		}
+	})
+
+	t.Run("GiveUp", func(t *testing.T) {
+		calls := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
+
+		baseURL = server.URL
+		_, err := FetchComment(context.Background(), 77)
+		if !IsTemporary(err) {
+			t.Errorf("Expected a temporary error, got: %v", err)
+		}
+		if calls != retryPolicy.maxAttempts {
+			t.Errorf("Expected %d requests, got %d", retryPolicy.maxAttempts, calls)
+		}
+	})
+
+	t.Run("NotTemporary", func(t *testing.T) {
+		calls := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.WriteHeader(http.StatusForbidden)
+		}))
+		defer server.Close()
+
+		baseURL = server.URL
+		_, err := FetchComment(context.Background(), 77)
+		var se *StatusError
+		if !errors.As(err, &se) || se.StatusCode != http.StatusForbidden {
+			t.Errorf("Expected a 403 StatusError, got: %v", err)
+		}
+		if calls != 1 {
+			t.Errorf("Expected 1 request, got %d", calls)
+		}
+	})
+}
+
+func TestTokenBucket(t *testing.T) {
+	tb := newTokenBucket(50, 2)
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := tb.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 2 burst tokens are free, the other 2 cost 20ms each
+	if elapsed := time.Since(start); elapsed < 35*time.Millisecond {
+		t.Errorf("Expected to be rate limited, but 4 tokens took only %v", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	tb = newTokenBucket(1, 1)
+	_ = tb.Wait(ctx) // uses the burst token
+	if err := tb.Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got: %v", err)
+	}
+	if tb.tokens < -0.5 {
+		t.Errorf("Expected the cancelled wait to give its token back, but the bucket is at %v", tb.tokens)
+	}
+}
+
+func TestAlgolia(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		switch r.URL.Path {
+		case "/items/42":
+			_, _ = w.Write([]byte(`{
+  "author": "whoishiring",
+  "children": [
+    {"author": "bro", "children": [{"author": "nag", "children": [], "created_at_i": 1727794999, "id": 60, "parent_id": 55, "text": "<p>reply</p>", "type": "comment"}], "created_at_i": 1727794816, "id": 55, "parent_id": 42, "text": "<p>Acme | Remote</p><p>goodjob</p>", "type": "comment"},
+    {"author": null, "children": [], "created_at_i": 1727794817, "id": 56, "parent_id": 42, "text": null, "type": "comment"}
+  ],
+  "created_at_i": 1727794816,
+  "id": 42,
+  "parent_id": null,
+  "text": "boilerplate",
+  "title": "Ask HN: Who is hiring? (October 2024)",
+  "type": "story"
+}`))
+		case "/items/77":
+			_, _ = w.Write([]byte(`{"author": "bro", "children": [], "created_at_i": 1727794816, "id": 77, "parent_id": 999, "text": "<p>goodjob</p>", "type": "comment"}`))
+		default:
+			t.Errorf("Unexpected request: %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+	algoliaBaseURL = server.URL
+
+	t.Run("Thread", func(t *testing.T) {
+		story, comments, err := FetchAlgoliaThread(context.Background(), 42)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectedStory := &Story{
+			Id:            42,
+			Kids:          []int{55, 56},
+			Time:          1727794816,
+			GoTime:        time.Unix(1727794816, 0),
+			Title:         "Ask HN: Who is hiring? (October 2024)",
+			FetchedTime:   story.FetchedTime, // no good way to test these
+			FetchedGoTime: story.FetchedGoTime,
+		}
+		if !reflect.DeepEqual(story, expectedStory) {
+			t.Errorf("Expected:\n  %#v\ngot:\n  %#v", expectedStory, story)
+		}
+		expectedComments := []*Comment{
+			{
+				Id:            55,
+				Parent:        42,
+				Text:          "Acme | Remote<p>goodjob",
+				Time:          1727794816,
+				GoTime:        time.Unix(1727794816, 0),
+				FetchedTime:   story.FetchedTime,
+				FetchedGoTime: story.FetchedGoTime,
+			},
+			{
+				Id:            56,
+				Parent:        42,
+				Time:          1727794817,
+				GoTime:        time.Unix(1727794817, 0),
+				FetchedTime:   story.FetchedTime,
+				FetchedGoTime: story.FetchedGoTime,
+				Deleted:       true,
+			},
+		}
+		if !reflect.DeepEqual(comments, expectedComments) {
+			t.Errorf("Expected:\n  %#v\ngot:\n  %#v", expectedComments, comments)
+		}
+	})
+
+	t.Run("Backend", func(t *testing.T) {
+		b, err := NewBackend(BackendAlgolia)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = b.FetchStory(context.Background(), 42)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// cached from the thread
+		c, err := b.FetchComment(context.Background(), 55)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Text != "Acme | Remote<p>goodjob" {
+			t.Errorf("Unexpected comment text: %q", c.Text)
+		}
+		// not cached
+		c, err = b.FetchComment(context.Background(), 77)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Parent != 999 || c.Text != "goodjob" {
+			t.Errorf("Unexpected comment: %#v", c)
+		}
+		// wrong type
+		_, err = b.FetchStory(context.Background(), 77)
+		if !errors.Is(err, ErrWrongItemType) {
+			t.Errorf("Expected ErrWrongItemType, got: %v", err)
+		}
+	})
+}
+
+func TestCassette(t *testing.T) {
+	oldPolicy, oldBaseURL := retryPolicy, baseURL
+	t.Cleanup(func() {
+		_ = UseCassette("", CassetteOff)
+		retryPolicy, baseURL = oldPolicy, oldBaseURL
+	})
+	retryPolicy.baseDelay = time.Millisecond
+	retryPolicy.maxDelay = 5 * time.Millisecond
+	path := filepath.Join(t.TempDir(), "cassette.jsonl")
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch {
+		case r.URL.Path == "/item/77.json" && calls == 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case r.URL.Path == "/item/77.json":
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{"by": "bro", "id": 77, "parent": 999, "text": "goodjob", "time": 1727794816, "type": "comment"}`))
+		case r.URL.Path == "/user/foouser/submitted.json":
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`[1,2,5]`))
+		default:
+			t.Errorf("Unexpected request: %s", r.URL.Path)
+		}
+	}))
+	baseURL = server.URL
+
+	// record
+	err := UseCassette(path, CassetteRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded, err := FetchComment(context.Background(), 77)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = FetchSubmissions(context.Background(), "foouser")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+
+	// replay, with the server gone
+	err = UseCassette(path, CassetteReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed, err := FetchComment(context.Background(), 77) // 503 and then success, like the original
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded.FetchedTime, recorded.FetchedGoTime = replayed.FetchedTime, replayed.FetchedGoTime
+	if !reflect.DeepEqual(recorded, replayed) {
+		t.Errorf("Expected:\n  %#v\ngot:\n  %#v", recorded, replayed)
+	}
+	subs, err := FetchSubmissions(context.Background(), "foouser")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(subs, []int{1, 2, 5}) {
+		t.Errorf("Unexpected submissions: %v", subs)
+	}
+	_, err = FetchComment(context.Background(), 78)
+	if !errors.Is(err, ErrNotInCassette) {
+		t.Errorf("Expected ErrNotInCassette, got: %v", err)
+	}
+
+	// replaying leaves the rate limit alone, and reconfiguring keeps the cassette
+	if limiter.rate != defaultRatePerSec {
+		t.Errorf("Expected the default rate limit while replaying, got %v/s", limiter.rate)
+	}
+	err = Configure(ClientOptions{BaseURL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = FetchSubmissions(context.Background(), "foouser")
+	if err != nil {
+		t.Errorf("Expected the cassette to survive Configure(), got: %v", err)
+	}
 }
@@ -0,0 +1,89 @@
+package hn
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// tokenBucket is a minimal token-bucket rate limiter.  Every request made by this package waits on the same bucket,
+// so all fetch workers share one budget no matter how many of them there are.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64 // tokens per second; <= 0 means unlimited
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(ratePerSec float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   ratePerSec,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait blocks until a token is available or the context is done.
+func (tb *tokenBucket) Wait(ctx context.Context) error {
+	tb.mu.Lock()
+	if tb.rate <= 0 {
+		tb.mu.Unlock()
+		return nil
+	}
+	now := time.Now()
+	tb.tokens += now.Sub(tb.last).Seconds() * tb.rate
+	if tb.tokens > tb.burst {
+		tb.tokens = tb.burst
+	}
+	tb.last = now
+	// Reserve our token even if that puts us in debt.  The debt is what we have to wait for, and it makes later
+	// callers wait behind us.
+	tb.tokens--
+	var wait time.Duration
+	if tb.tokens < 0 {
+		wait = time.Duration(-tb.tokens / tb.rate * float64(time.Second))
+	}
+	tb.mu.Unlock()
+
+	if wait == 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		// we never made the request, so give the token back
+		tb.mu.Lock()
+		tb.tokens = min(tb.tokens+1, tb.burst)
+		tb.mu.Unlock()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+const (
+	defaultRatePerSec = 10
+	defaultBurst      = 10
+)
+
+var limiter = newTokenBucket(defaultRatePerSec, defaultBurst)
+
+// SetRateLimit sets the maximum sustained requests per second (shared by all callers) and how many requests may be
+// made in a burst.  A rate <= 0 disables limiting.
+func SetRateLimit(ratePerSec float64, burst int) {
+	if burst < 1 {
+		burst = 1
+	}
+	limiter.mu.Lock()
+	limiter.rate = ratePerSec
+	limiter.burst = float64(burst)
+	limiter.tokens = float64(burst)
+	limiter.last = time.Now()
+	limiter.mu.Unlock()
+}
@@ -0,0 +1,202 @@
+// Package jobheader parses the first line of a Who's Hiring comment, which by convention is a pipe-delimited summary of
+// the job, e.g.:
+//
+//	Acme Corp | Senior SRE | Berlin or Remote (EU) | €90k-€110k | Full-time | https://acme.example/jobs
+//
+// Nobody follows the convention exactly, so this is all best-effort.
+package jobheader
+
+import (
+	"github.com/mwinters0/hnjobs/plaintext"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// JobHeader is the parsed header line.  Each field is the (plain text) segment of the header it came from, or "" if
+// we couldn't find one.  Segments we couldn't classify go in Extra.
+type JobHeader struct {
+	Company    string
+	Role       string
+	Location   string
+	Remote     string // whatever the poster said about remote / onsite / hybrid
+	Employment string // full-time, contract, etc.
+	Salary     string
+	Visa       string
+	URL        string
+	Extra      []string `json:",omitempty"`
+}
+
+// FieldNames are the fields which can be retrieved with Field()
+var FieldNames = []string{"company", "role", "location", "remote", "employment", "salary", "visa", "url"}
+
+// Field returns a field by its (lowercase) name, for use by e.g. scoring rules
+func (h *JobHeader) Field(name string) (string, bool) {
+	switch name {
+	case "company":
+		return h.Company, true
+	case "role":
+		return h.Role, true
+	case "location":
+		return h.Location, true
+	case "remote":
+		return h.Remote, true
+	case "employment":
+		return h.Employment, true
+	case "salary":
+		return h.Salary, true
+	case "visa":
+		return h.Visa, true
+	case "url":
+		return h.URL, true
+	default:
+		return "", false
+	}
+}
+
+// ScopeNames are the parts of the whole posting which can be retrieved with Scope(), as opposed to FieldNames which are
+// parts of the header line
+var ScopeNames = []string{"header", "first_paragraph", "body", "urls"}
+
+// Scope returns part of a job comment by its name.  text is the raw HTML from the HN API, and so is the result, except
+// for "urls" which is the link targets one per line.
+func Scope(text string, name string) (string, bool) {
+	line, body := splitHeader(text)
+	switch name {
+	case "header":
+		return line, true
+	case "body":
+		return body, true
+	case "first_paragraph":
+		// the first thing after the header, which is usually the company's pitch
+		for _, p := range strings.Split(body, "<p>") {
+			if strings.TrimSpace(p) != "" {
+				return p, true
+			}
+		}
+		return "", true
+	case "urls":
+		var urls []string
+		for _, m := range hrefRegex.FindAllStringSubmatch(text, -1) {
+			urls = append(urls, html.UnescapeString(m[1]))
+		}
+		return strings.Join(urls, "\n"), true
+	default:
+		return "", false
+	}
+}
+
+// splitHeader splits the text into the header line and everything after it
+func splitHeader(text string) (string, string) {
+	// HN separates paragraphs with <p>
+	end := len(text)
+	if i := strings.Index(text, "<p>"); i >= 0 {
+		end = i
+	}
+	if i := strings.IndexByte(text[:end], '\n'); i >= 0 {
+		end = i
+	}
+	return text[:end], strings.TrimPrefix(strings.TrimPrefix(text[end:], "\n"), "<p>")
+}
+
+// Summary is a one-line human-readable version of everything but the company, e.g. for display under the job.
+func (h *JobHeader) Summary() string {
+	var parts []string
+	for _, f := range []string{h.Role, h.Location, h.Remote, h.Employment, h.Salary, h.Visa} {
+		if f != "" {
+			parts = append(parts, f)
+		}
+	}
+	return strings.Join(parts, " · ")
+}
+
+var (
+	hrefRegex       = regexp.MustCompile(`<a href="([^"]+)"`)
+	urlRegex        = regexp.MustCompile(`(?i)^(https?://)?(www\.)?[a-z0-9-]+(\.[a-z0-9-]+)*\.[a-z]{2,}(/\S*)?$`)
+	salaryRegex     = regexp.MustCompile(`(?i)[$€£¥]\s*\d|\d\s*k\b|\b(usd|eur|gbp|cad|aud|chf|salary|compensation|equity|ote)\b|\d\s*(/\s*hr|/\s*hour|per hour)`)
+	visaRegex       = regexp.MustCompile(`(?i)\bvisas?\b|\bsponsor`)
+	remoteRegex     = regexp.MustCompile(`(?i)\b(remote(-first|-friendly|-ok)?|on-?site|on site|in-office|in office|hybrid|wfh)\b`)
+	employmentRegex = regexp.MustCompile(`(?i)\b(full[- ]?time|part[- ]?time|contract(or)?|freelance|internships?|permanent|ft|pt)\b`)
+	roleRegex       = regexp.MustCompile(`(?i)\b(engineers?|engineering|developers?|devs?|swe|sre|devops|designers?|managers?|scientists?|analysts?|architects?|leads?|head of|directors?|researchers?|cto|vp|founding|programmers?|administrators?|specialists?|consultants?|product|marketing|sales|recruiters?|roles|positions|frontend|front-end|backend|back-end|full[- ]?stack|ml|ai|data|interns?)\b`)
+	leftoverRegex   = regexp.MustCompile(`[[:alpha:]]{2,}`)
+)
+
+// Parse parses the header line of a job comment.  text is the raw HTML from the HN API.
+func Parse(text string) JobHeader {
+	h := JobHeader{}
+	line, _ := splitHeader(text)
+
+	// Company is whatever comes before the first delimiter.  We look at the unstripped text for this so that a header
+	// starting with a link (i.e. no company name) doesn't produce a company.
+	unescaped := html.UnescapeString(line)
+	if i := strings.IndexAny(unescaped, "(|<"); i >= 0 {
+		h.Company = strings.TrimSpace(unescaped[:i])
+	} else {
+		h.Company = strings.TrimSpace(unescaped)
+	}
+
+	var href string
+	if m := hrefRegex.FindStringSubmatch(line); m != nil {
+		href = html.UnescapeString(m[1])
+	}
+	plain := plaintext.FromHTML(line)
+	segments := strings.Split(plain, "|")
+	for _, seg := range segments[1:] {
+		seg = strings.TrimSpace(seg)
+		if seg == "" {
+			continue
+		}
+		classify(&h, seg)
+	}
+	if h.URL == "" {
+		h.URL = href
+	}
+	return h
+}
+
+// classify assigns the segment to every empty field which it looks like.  Location has no good heuristic, so it gets
+// the first segment which doesn't look like anything else.
+func classify(h *JobHeader, seg string) {
+	if urlRegex.MatchString(seg) {
+		if h.URL == "" {
+			h.URL = seg
+		}
+		return
+	}
+	matched := false
+	onlyRemote := true
+	check := func(field *string, r *regexp.Regexp, isRemote bool) {
+		if !r.MatchString(seg) {
+			return
+		}
+		matched = true
+		if !isRemote {
+			onlyRemote = false
+		}
+		if *field == "" {
+			*field = seg
+		}
+	}
+	check(&h.Salary, salaryRegex, false)
+	check(&h.Visa, visaRegex, false)
+	check(&h.Remote, remoteRegex, true)
+	check(&h.Employment, employmentRegex, false)
+	check(&h.Role, roleRegex, false)
+
+	if matched && onlyRemote {
+		// e.g. "NYC or Remote", "Remote (US)"
+		leftover := remoteRegex.ReplaceAllString(seg, "")
+		if h.Location == "" && leftoverRegex.MatchString(strings.ReplaceAll(leftover, " or ", " ")) {
+			h.Location = seg
+		}
+		return
+	}
+	if matched {
+		return
+	}
+	if h.Location == "" {
+		h.Location = seg
+		return
+	}
+	h.Extra = append(h.Extra, seg)
+}
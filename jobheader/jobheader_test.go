@@ -0,0 +1,126 @@
+package jobheader
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want JobHeader
+	}{
+		{
+			name: "Typical",
+			text: `Acme Corp | Senior SRE | Berlin, Germany | ONSITE | Full-time | €90k-€110k<p>We make anvils.`,
+			want: JobHeader{
+				Company:    "Acme Corp",
+				Role:       "Senior SRE",
+				Location:   "Berlin, Germany",
+				Remote:     "ONSITE",
+				Employment: "Full-time",
+				Salary:     "€90k-€110k",
+			},
+		},
+		{
+			name: "LinkAndVisa",
+			text: `Widgets Inc (YC W21) | Backend Engineer | NYC or Remote (US) | $150k-$200k + equity | Visa sponsorship | <a href="https:&#x2F;&#x2F;widgets.example&#x2F;jobs" rel="nofollow">https:&#x2F;&#x2F;widgets.example&#x2F;jobs</a><p>Hi`,
+			want: JobHeader{
+				Company:  "Widgets Inc",
+				Role:     "Backend Engineer",
+				Location: "NYC or Remote (US)",
+				Remote:   "NYC or Remote (US)",
+				Salary:   "$150k-$200k + equity",
+				Visa:     "Visa sponsorship",
+				URL:      "https://widgets.example/jobs",
+			},
+		},
+		{
+			name: "RemoteOnly",
+			text: `Foo | REMOTE | Rust developer | foo.example.com`,
+			want: JobHeader{
+				Company: "Foo",
+				Role:    "Rust developer",
+				Remote:  "REMOTE",
+				URL:     "foo.example.com",
+			},
+		},
+		{
+			name: "Unclassified",
+			text: `Bar &amp; Baz | London | Series A | Climate`,
+			want: JobHeader{
+				Company:  "Bar & Baz",
+				Location: "London",
+				Extra:    []string{"Series A", "Climate"},
+			},
+		},
+		{
+			name: "NoPipes",
+			text: `Quux is hiring engineers in Paris.<p>Details`,
+			want: JobHeader{
+				Company: "Quux is hiring engineers in Paris.",
+			},
+		},
+		{
+			name: "LeadingLink",
+			text: `<a href="https:&#x2F;&#x2F;joker.example">joker.example</a> | Engineer`,
+			want: JobHeader{
+				Role: "Engineer",
+				URL:  "https://joker.example",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Parse(tt.text)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got  %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestField(t *testing.T) {
+	h := Parse("Acme | SRE | Remote")
+	for _, name := range FieldNames {
+		if _, ok := h.Field(name); !ok {
+			t.Errorf("FieldNames contains %q but Field() doesn't know it", name)
+		}
+	}
+	if v, _ := h.Field("remote"); v != "Remote" {
+		t.Errorf("expected remote to be %q, got %q", "Remote", v)
+	}
+	if _, ok := h.Field("nope"); ok {
+		t.Error("expected unknown field to fail")
+	}
+}
+
+func TestScope(t *testing.T) {
+	text := `Acme | Senior SRE | Berlin<p>We make anvils.<p>You'll work with senior engineers. <a href="https:&#x2F;&#x2F;acme.example&#x2F;jobs" rel="nofollow">https:&#x2F;&#x2F;acme.example&#x2F;jobs</a>`
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"header", "Acme | Senior SRE | Berlin"},
+		{"first_paragraph", "We make anvils."},
+		{"body", `We make anvils.<p>You'll work with senior engineers. <a href="https:&#x2F;&#x2F;acme.example&#x2F;jobs" rel="nofollow">https:&#x2F;&#x2F;acme.example&#x2F;jobs</a>`},
+		{"urls", "https://acme.example/jobs"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Scope(text, tt.name)
+			if !ok || got != tt.want {
+				t.Errorf("expected %q, got %q (ok %v)", tt.want, got, ok)
+			}
+		})
+	}
+	for _, name := range ScopeNames {
+		if _, ok := Scope(text, name); !ok {
+			t.Errorf("ScopeNames contains %q but Scope() doesn't know it", name)
+		}
+	}
+	if got, _ := Scope("Acme | SRE\nWe make anvils.", "first_paragraph"); got != "We make anvils." {
+		t.Errorf("expected newline to end the header, got %q", got)
+	}
+}
@@ -0,0 +1,35 @@
+// Package plaintext converts the HTML in HN comments to plain text, so that everything which reads a job (scoring,
+// parsing and the TUI) sees the same thing.  HN only uses a few tags: <p> between paragraphs, <a href> for links, <i>
+// and <pre><code>.
+package plaintext
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	tagRegex       = regexp.MustCompile(`<[^>]*>`)
+	formatTagRegex = regexp.MustCompile(`^</?(a|pre|i)\b`)
+)
+
+// FromHTML returns the text the way it looks on HN: paragraphs are separated by a blank line, links are just their
+// text and entities like &#x2F; are unescaped.
+func FromHTML(s string) string {
+	s = strings.ReplaceAll(s, "<p>", "\n\n")
+	return html.UnescapeString(tagRegex.ReplaceAllString(s, ""))
+}
+
+// FromHTMLKeepFormatting is FromHTML but leaves the <a href>, <pre> and <i> tags in, for the TUI which turns them into
+// clickable links, code blocks and italics.  Only the tags change, so the text is the same as FromHTML's.
+func FromHTMLKeepFormatting(s string) string {
+	s = strings.ReplaceAll(s, "<p>", "\n\n")
+	s = tagRegex.ReplaceAllStringFunc(s, func(tag string) string {
+		if formatTagRegex.MatchString(tag) {
+			return tag
+		}
+		return ""
+	})
+	return html.UnescapeString(s)
+}
@@ -0,0 +1,47 @@
+package plaintext
+
+import (
+	"testing"
+)
+
+func TestFromHTML(t *testing.T) {
+	tests := []struct {
+		html string
+		want string
+	}{
+		{"Acme | SRE | Remote&#x2F;Hybrid", "Acme | SRE | Remote/Hybrid"},
+		{"Acme<p>We use <i>C++</i> &amp; Go", "Acme\n\nWe use C++ & Go"},
+		{`Apply: <a href="https:&#x2F;&#x2F;acme.example&#x2F;jobs" rel="nofollow">https:&#x2F;&#x2F;acme.example&#x2F;jobs</a>`, "Apply: https://acme.example/jobs"},
+		{"<pre><code>  go build\n</code></pre>", "  go build\n"},
+		{"a &lt;b&gt; tag", "a <b> tag"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.html, func(t *testing.T) {
+			if got := FromHTML(tt.html); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFromHTMLKeepFormatting(t *testing.T) {
+	tests := []struct {
+		html string
+		want string
+	}{
+		{
+			`See <a href="https:&#x2F;&#x2F;acme.example" rel="nofollow">acme.example</a>`,
+			`See <a href="https://acme.example" rel="nofollow">acme.example</a>`,
+		},
+		{"We use <i>Go</i>", "We use <i>Go</i>"},
+		{"Run:<p><pre><code>  go build\n</code></pre>", "Run:\n\n<pre>  go build\n</pre>"},
+		{"<b>bold</b> &amp; <abbr>abbr</abbr>", "bold & abbr"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.html, func(t *testing.T) {
+			if got := FromHTMLKeepFormatting(tt.html); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
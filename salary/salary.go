@@ -0,0 +1,199 @@
+// Package salary finds and normalizes the compensation in a job posting, e.g. "$150k-$200k", "€90.000",
+// "120-160K USD + equity" or "$80/hr".
+package salary
+
+import (
+	"github.com/mwinters0/hnjobs/plaintext"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type Period string
+
+const (
+	PeriodUnknown    Period = ""
+	PeriodAnnual     Period = "annual"
+	PeriodHourly     Period = "hourly"
+	PeriodEquityOnly Period = "equity" // no cash amount mentioned, just equity
+)
+
+// hoursPerYear is used to compare hourly rates with annual salaries
+const hoursPerYear = 2080
+
+// anything outside these is probably not a salary
+const (
+	minAnnual = 10_000
+	maxAnnual = 5_000_000
+	minHourly = 5
+	maxHourly = 2_000
+)
+
+// Salary is in whole units of Currency per Period.  Min or Max is 0 if unknown, e.g. "$150k+" only has a Min.
+type Salary struct {
+	Min      int64
+	Max      int64
+	Currency string // ISO 4217, e.g. "USD"
+	Period   Period
+}
+
+// Annualized returns Min and Max as annual amounts
+func (s *Salary) Annualized() (int64, int64) {
+	if s.Period == PeriodHourly {
+		return s.Min * hoursPerYear, s.Max * hoursPerYear
+	}
+	return s.Min, s.Max
+}
+
+var (
+	// amount: an optional currency symbol, a number, and an optional k / M suffix
+	amountRegex   = regexp.MustCompile(`(?i)(CA\$|C\$|A\$|US\$|[$€£¥])?\s?(\d[\d.,]*)\s?([km])?\b`)
+	rangeRegex    = regexp.MustCompile(`(?i)^\s*(-|–|—|to)\s*$`)
+	codeRegex     = regexp.MustCompile(`\b(USD|EUR|GBP|CAD|AUD|CHF|JPY|SEK|NOK|DKK|PLN|INR|SGD|NZD)\b`)
+	hourlyRegex   = regexp.MustCompile(`(?i)/\s?(hr|hour|h)\b|\bper hour\b|\bhourly\b|\san hour\b`)
+	equityRegex   = regexp.MustCompile(`(?i)\bequity\b`)
+	cashRegex     = regexp.MustCompile(`(?i)\b(salary|salaries|competitive|pay|pays|paid)\b`)
+	symbolToCode  = map[string]string{"$": "USD", "US$": "USD", "€": "EUR", "£": "GBP", "¥": "JPY", "CA$": "CAD", "C$": "CAD", "A$": "AUD"}
+	thousandsSeps = regexp.MustCompile(`^\d{1,3}([.,]\d{3})+$`)
+	withDecimals  = regexp.MustCompile(`^(\d{1,3}(?:[.,]\d{3})+)([.,])(\d{1,2})$`)
+)
+
+type amount struct {
+	value     float64
+	symbol    string
+	suffix    string
+	start     int
+	end       int
+	hasSuffix bool
+}
+
+// Parse finds the salary in a short piece of plain text, typically the salary segment of the header.  If requireSymbol
+// is set then only amounts with a currency symbol count, which cuts down on false positives in longer text.  The zero
+// Salary is returned if nothing plausible was found.
+func Parse(text string, requireSymbol bool) Salary {
+	var amounts []amount
+	for _, m := range amountRegex.FindAllStringSubmatchIndex(text, -1) {
+		a := amount{start: m[0], end: m[1]}
+		if m[2] >= 0 {
+			a.symbol = text[m[2]:m[3]]
+		}
+		v, ok := parseNumber(text[m[4]:m[5]])
+		if !ok {
+			continue
+		}
+		a.value = v
+		if m[6] >= 0 {
+			a.suffix = strings.ToLower(text[m[6]:m[7]])
+			a.hasSuffix = true
+		}
+		amounts = append(amounts, a)
+	}
+
+	s := Salary{}
+	for i := 0; i < len(amounts); i++ {
+		lo := amounts[i]
+		var hi *amount
+		if i+1 < len(amounts) && rangeRegex.MatchString(text[lo.end:amounts[i+1].start]) {
+			hi = &amounts[i+1]
+		}
+		symbol := lo.symbol
+		if symbol == "" && hi != nil {
+			symbol = hi.symbol
+		}
+		if requireSymbol && symbol == "" {
+			continue
+		}
+		if lo.suffix == "m" || (hi != nil && hi.suffix == "m") {
+			continue // "$20M seed round"
+		}
+		// "120-160K": the suffix applies to both
+		if !lo.hasSuffix && hi != nil && hi.suffix == "k" {
+			lo.suffix = "k"
+		}
+		min := applySuffix(lo)
+		max := int64(0)
+		if hi != nil {
+			max = applySuffix(*hi)
+		}
+
+		period := PeriodAnnual
+		end := lo.end
+		if hi != nil {
+			end = hi.end
+		}
+		tail := text[end:]
+		if len(tail) > 12 {
+			tail = tail[:12]
+		}
+		if hourlyRegex.MatchString(tail) {
+			period = PeriodHourly
+		}
+		if !plausible(min, period) || (max != 0 && (!plausible(max, period) || max < min)) {
+			if hi != nil {
+				i++
+			}
+			continue
+		}
+		s.Min, s.Max, s.Period = min, max, period
+		s.Currency = symbolToCode[strings.ToUpper(symbol)]
+		// just the amount and its tail, not e.g. "EUR" somewhere later in the job
+		if c := codeRegex.FindString(text[lo.start : end+len(tail)]); c != "" {
+			s.Currency = c
+		}
+		return s
+	}
+	// "competitive salary + equity" has cash too, we just don't know how much
+	if equityRegex.MatchString(text) && !cashRegex.MatchString(text) && !requireSymbol {
+		s.Period = PeriodEquityOnly
+	}
+	return s
+}
+
+// Extract finds the salary of a job.  headerSalary is the salary segment of the job's header (see jobheader), which is
+// preferred.  Otherwise we look for an amount with a currency symbol anywhere in text, which is HTML.
+func Extract(headerSalary string, text string) Salary {
+	fromHeader := Parse(headerSalary, false)
+	if fromHeader.Min != 0 || fromHeader.Max != 0 {
+		return fromHeader
+	}
+	plain := plaintext.FromHTML(text)
+	if fromBody := Parse(plain, true); fromBody.Period != PeriodUnknown {
+		return fromBody
+	}
+	return fromHeader // maybe equity-only
+}
+
+// parseNumber handles both "90,000" and "90.000" (and "90.5", "90,000.00" and "90.000,00")
+func parseNumber(s string) (float64, bool) {
+	s = strings.TrimRight(s, ".,")
+	if s == "" {
+		return 0, false
+	}
+	if m := withDecimals.FindStringSubmatch(s); m != nil && !strings.Contains(m[1], m[2]) {
+		// the last separator is the only one of its kind, so it's the decimal point
+		s = strings.NewReplacer(".", "", ",", "").Replace(m[1]) + "." + m[3]
+	} else if thousandsSeps.MatchString(s) {
+		s = strings.NewReplacer(".", "", ",", "").Replace(s)
+	} else {
+		s = strings.ReplaceAll(s, ",", ".")
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false
+	}
+	return v, true
+}
+
+func applySuffix(a amount) int64 {
+	if a.suffix == "k" {
+		return int64(a.value * 1000)
+	}
+	return int64(a.value)
+}
+
+func plausible(v int64, p Period) bool {
+	if p == PeriodHourly {
+		return v >= minHourly && v <= maxHourly
+	}
+	return v >= minAnnual && v <= maxAnnual
+}
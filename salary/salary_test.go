@@ -0,0 +1,67 @@
+package salary
+
+import (
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		text string
+		want Salary
+	}{
+		{"$150k-$200k", Salary{150_000, 200_000, "USD", PeriodAnnual}},
+		{"€90.000", Salary{90_000, 0, "EUR", PeriodAnnual}},
+		{"€90.000,00", Salary{90_000, 0, "EUR", PeriodAnnual}},
+		{"$90,000.00 - $120,000.00", Salary{90_000, 120_000, "USD", PeriodAnnual}},
+		{"120-160K USD + equity", Salary{120_000, 160_000, "USD", PeriodAnnual}},
+		{"$80/hr", Salary{80, 0, "USD", PeriodHourly}},
+		{"$60 - $90 per hour", Salary{60, 90, "USD", PeriodHourly}},
+		{"£65,000 to £80,000", Salary{65_000, 80_000, "GBP", PeriodAnnual}},
+		{"CA$130k+", Salary{130_000, 0, "CAD", PeriodAnnual}},
+		{"90.5k EUR", Salary{90_500, 0, "EUR", PeriodAnnual}},
+		{"Competitive salary + equity", Salary{}},
+		{"Good pay and equity", Salary{}},
+		{"Equity only", Salary{Period: PeriodEquityOnly}},
+		{"$150k, payroll in EUR for Berlin", Salary{150_000, 0, "USD", PeriodAnnual}},
+		{"Series A, 12 people", Salary{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got := Parse(tt.text, false)
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtract(t *testing.T) {
+	t.Run("Header", func(t *testing.T) {
+		got := Extract("$150k-$200k", "Acme | SRE | $150k-$200k<p>We have 10k users")
+		want := Salary{150_000, 200_000, "USD", PeriodAnnual}
+		if got != want {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+	})
+	t.Run("Body", func(t *testing.T) {
+		got := Extract("", "Acme | SRE<p>We raised $20M and have 10k users.<p>Pay: <i>$140,000</i> - $170,000")
+		want := Salary{140_000, 170_000, "USD", PeriodAnnual}
+		if got != want {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+	})
+	t.Run("BodyNoSymbol", func(t *testing.T) {
+		got := Extract("", "Acme | SRE<p>We serve 50,000 customers with equity")
+		if got != (Salary{}) {
+			t.Errorf("expected nothing, got %+v", got)
+		}
+	})
+}
+
+func TestAnnualized(t *testing.T) {
+	s := Salary{50, 100, "USD", PeriodHourly}
+	min, max := s.Annualized()
+	if min != 50*hoursPerYear || max != 100*hoursPerYear {
+		t.Errorf("got %d-%d", min, max)
+	}
+}
@@ -1,11 +1,19 @@
 package scoring
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/mwinters0/hnjobs/config"
 	"github.com/mwinters0/hnjobs/db"
+	"github.com/mwinters0/hnjobs/gazetteer"
+	"github.com/mwinters0/hnjobs/jobheader"
+	"github.com/mwinters0/hnjobs/plaintext"
+	"github.com/mwinters0/hnjobs/techstack"
+	"github.com/mwinters0/hnjobs/workmode"
 	"regexp"
 	"slices"
+	"strings"
+	"sync"
 )
 
 type RuleType int
@@ -13,6 +21,14 @@ type RuleType int
 const (
 	TextFound RuleType = iota
 	TextMissing
+	Numeric
+	WorkModeIs
+	RemoteCompatible
+	LocationWithin
+	TechIs
+	All
+	Any
+	Not
 )
 
 func (rt RuleType) String() string {
@@ -21,6 +37,22 @@ func (rt RuleType) String() string {
 		return "TextFound"
 	case TextMissing:
 		return "TextMissing"
+	case Numeric:
+		return "Numeric"
+	case WorkModeIs:
+		return "WorkModeIs"
+	case RemoteCompatible:
+		return "RemoteCompatible"
+	case LocationWithin:
+		return "LocationWithin"
+	case TechIs:
+		return "TechIs"
+	case All:
+		return "All"
+	case Any:
+		return "Any"
+	case Not:
+		return "Not"
 	default:
 		panic(fmt.Errorf("unhandled rule type %d", rt))
 	}
@@ -28,8 +60,14 @@ func (rt RuleType) String() string {
 
 type Rule struct {
 	config.ScoringRule
-	RuleType RuleType
-	Regex    *regexp.Regexp
+	RuleType  RuleType
+	Regex     *regexp.Regexp
+	Condition *config.NumericCondition
+	Mode      workmode.Mode
+	RadiusKm  float64
+	Sub       []*Rule // the conditions of an All, Any or Not rule
+	Index     int     // position in the config, for top-level rules
+	JSON      string  // the rule as it is in the config, for top-level rules
 }
 
 func newRuleFromConf(confRule *config.ScoringRule) (*Rule, error) {
@@ -38,17 +76,65 @@ func newRuleFromConf(confRule *config.ScoringRule) (*Rule, error) {
 		rt = TextFound
 	} else if confRule.TextMissing != "" {
 		rt = TextMissing
+	} else if confRule.Numeric != "" {
+		rt = Numeric
+	} else if confRule.WorkMode != "" {
+		rt = WorkModeIs
+	} else if confRule.RemoteCompatible != nil {
+		rt = RemoteCompatible
+	} else if confRule.LocationWithinKm != nil {
+		rt = LocationWithin
+	} else if confRule.Tech != "" {
+		rt = TechIs
+	} else if len(confRule.All) > 0 {
+		rt = All
+	} else if len(confRule.Any) > 0 {
+		rt = Any
+	} else if confRule.Not != nil {
+		rt = Not
 	}
 	r := &Rule{
-		*confRule,
-		rt,
-		nil,
+		ScoringRule: *confRule,
+		RuleType:    rt,
 	}
 	switch rt {
 	case TextFound:
 		r.Regex = regexp.MustCompile(confRule.TextFound)
 	case TextMissing:
 		r.Regex = regexp.MustCompile(confRule.TextMissing)
+	case Numeric:
+		var err error
+		r.Condition, err = config.ParseNumericCondition(confRule.Numeric)
+		if err != nil {
+			return nil, err
+		}
+	case WorkModeIs:
+		var ok bool
+		r.Mode, ok = workmode.ParseMode(confRule.WorkMode)
+		if !ok {
+			return nil, fmt.Errorf("unknown work_mode %q", confRule.WorkMode)
+		}
+	case RemoteCompatible:
+	case LocationWithin:
+		r.RadiusKm = *confRule.LocationWithinKm
+		if r.RadiusKm == 0 {
+			r.RadiusKm = config.GetConfig().Profile.RadiusKm
+		}
+	case TechIs:
+	case All, Any, Not:
+		subConfs := confRule.All
+		if rt == Any {
+			subConfs = confRule.Any
+		} else if rt == Not {
+			subConfs = []config.ScoringRule{*confRule.Not}
+		}
+		for _, subConf := range subConfs {
+			sub, err := newRuleFromConf(&subConf)
+			if err != nil {
+				return nil, err
+			}
+			r.Sub = append(r.Sub, sub)
+		}
 	default:
 		return nil, fmt.Errorf("unhandled rule type %d", rt)
 	}
@@ -56,54 +142,102 @@ func newRuleFromConf(confRule *config.ScoringRule) (*Rule, error) {
 }
 
 var rules []*Rule
+var rulesMutex sync.RWMutex // fetch workers score concurrently, and the TUI can reload the rules during a fetch
+
+// for RemoteCompatible and LocationWithin
+var profileCountry string
+var profileTZ *float64
+var profileHomeLat, profileHomeLon float64
 
 func ReloadRules() error {
+	profile := config.GetConfig().Profile
+	tz, err := profile.UTCOffset()
+	if err != nil {
+		return err
+	}
+	homeLat, homeLon, hasHome, err := profile.HomeLatLon()
+	if err != nil {
+		return err
+	}
 	// create Rule list from config
 	confRules := config.GetConfig().Scoring.Rules
-	rules = make([]*Rule, len(confRules))
+	newRules := make([]*Rule, len(confRules))
 	for i, confRule := range confRules {
 		r, err := newRuleFromConf(&confRule)
 		if err != nil {
 			return err
 		}
-		rules[i] = r
+		var j strings.Builder
+		enc := json.NewEncoder(&j)
+		enc.SetEscapeHTML(false) // for e.g. "salary_max >= 1"
+		err = enc.Encode(confRule)
+		if err != nil {
+			return err
+		}
+		r.Index = i
+		r.JSON = strings.TrimSpace(j.String())
+		if !hasHome && usesLocation(r) {
+			// otherwise we'd measure from 0,0
+			return fmt.Errorf("rule %s needs your `home` in `profile`", r.JSON)
+		}
+		newRules[i] = r
 	}
+	rulesMutex.Lock()
+	defer rulesMutex.Unlock()
+	rules = newRules
+	profileCountry = profile.Country
+	profileTZ = tz
+	profileHomeLat, profileHomeLon = homeLat, homeLon
 	return nil
 }
 
+// usesLocation is whether the rule or any of its conditions is a LocationWithin rule
+func usesLocation(r *Rule) bool {
+	return r.RuleType == LocationWithin || slices.ContainsFunc(r.Sub, usesLocation)
+}
+
 func GetRules() []*Rule {
-	if len(rules) == 0 {
+	rulesMutex.RLock()
+	loaded := len(rules) > 0
+	rulesMutex.RUnlock()
+	if !loaded {
 		err := ReloadRules()
 		if err != nil {
 			panic(err)
 		}
 	}
+	rulesMutex.RLock()
+	defer rulesMutex.RUnlock()
 	return rules
 }
 
 func ScoreDBComment(dbc *db.Job) int {
-	if len(rules) == 0 {
-		err := ReloadRules()
-		if err != nil {
-			panic(err)
-		}
-	}
+	rs := GetRules()
+	rulesMutex.RLock() // for the profile
+	defer rulesMutex.RUnlock()
 	dbc.Score = 0
-	for _, r := range rules {
-		applyRule(r, dbc)
+	dbc.Why = nil
+	dbc.WhyNot = nil
+	dbc.RuleHits = nil
+	plain := plaintext.FromHTML(dbc.Text)
+	for _, r := range rs {
+		applyRule(r, dbc, plain)
 	}
 	return dbc.Score
 }
 
-func applyRule(rule *Rule, dbc *db.Job) {
-	shouldMatch := true //is this a regular Rule (Regex should return true) or an inverse Rule (should return false)?
-	if rule.RuleType == TextMissing {
-		shouldMatch = false
-	}
-	matched := rule.Regex.MatchString(dbc.Text)
-	if matched == shouldMatch {
+// applyRule adds the rule's score and tags to the job if it matches.  plain is the job's text as plain text.
+func applyRule(rule *Rule, dbc *db.Job, plain string) {
+	applies, known := matches(rule, dbc, plain)
+	if applies && known {
 		//Rule applies
 		dbc.Score = dbc.Score + rule.Score
+		dbc.RuleHits = append(dbc.RuleHits, &db.RuleHit{
+			RuleIndex: rule.Index,
+			Rule:      rule.JSON,
+			Points:    rule.Score,
+			Matches:   spans(rule, plain),
+		})
 		for _, y := range rule.TagsWhy {
 			if !slices.Contains(dbc.Why, y) {
 				dbc.Why = append(dbc.Why, y)
@@ -116,3 +250,180 @@ func applyRule(rule *Rule, dbc *db.Job) {
 		}
 	}
 }
+
+// matches evaluates the rule's condition.  known is false when the job doesn't say either way, e.g. a numeric salary
+// rule on a job with no salary.  That's different from not matching, because "not" of it is still unknown.
+func matches(rule *Rule, dbc *db.Job, plain string) (applies bool, known bool) {
+	switch rule.RuleType {
+	case TextFound, TextMissing:
+		shouldMatch := true //is this a regular Rule (Regex should return true) or an inverse Rule (should return false)?
+		if rule.RuleType == TextMissing {
+			shouldMatch = false
+		}
+		text := plain
+		if rule.RawHTML {
+			text = dbc.Text
+		}
+		if rule.Field != "" {
+			var ok bool
+			text, ok = dbc.Header.Field(rule.Field) // already plain text
+			if !ok {
+				text, _ = jobheader.Scope(dbc.Text, rule.Field) // validated by config
+				if !rule.RawHTML {
+					text = plaintext.FromHTML(text)
+				}
+			}
+		}
+		applies = rule.Regex.MatchString(text) == shouldMatch
+	case Numeric:
+		v, known := numericValue(rule.Condition.Field, dbc)
+		if !known {
+			// e.g. no salary listed, so we can't say either way
+			return false, false
+		}
+		applies = rule.Condition.Matches(v)
+	case WorkModeIs:
+		applies = dbc.WorkMode.Mode == rule.Mode
+	case RemoteCompatible:
+		if dbc.WorkMode.Mode != workmode.ModeRemote && dbc.WorkMode.Mode != workmode.ModeHybrid {
+			return false, false
+		}
+		applies = dbc.WorkMode.Allows(profileCountry, profileTZ) == *rule.RemoteCompatible
+	case LocationWithin:
+		if len(dbc.Places) == 0 {
+			return false, false
+		}
+		for _, key := range dbc.Places {
+			p := gazetteer.Get(key)
+			if p != nil && gazetteer.DistanceKm(profileHomeLat, profileHomeLon, p.Lat, p.Lon) <= rule.RadiusKm {
+				applies = true
+				break
+			}
+		}
+	case TechIs:
+		applies = slices.Contains(dbc.Tech, rule.Tech)
+	case All:
+		// false beats unknown, since one false condition is enough to know
+		known = true
+		for _, sub := range rule.Sub {
+			subApplies, subKnown := matches(sub, dbc, plain)
+			if subKnown && !subApplies {
+				return false, true
+			}
+			known = known && subKnown
+		}
+		return known, known
+	case Any:
+		// and true beats unknown
+		known = true
+		for _, sub := range rule.Sub {
+			subApplies, subKnown := matches(sub, dbc, plain)
+			if subKnown && subApplies {
+				return true, true
+			}
+			known = known && subKnown
+		}
+		return false, known
+	case Not:
+		applies, known = matches(rule.Sub[0], dbc, plain)
+		return !applies && known, known
+	}
+	return applies, true
+}
+
+// RuleResult is how one rule did against a job, for debugging rules
+type RuleResult struct {
+	Rule    *Rule
+	Matched bool
+	Known   bool     // false if the job doesn't say either way, e.g. a salary rule on a job with no salary
+	Spans   [][2]int // what the rule's text and tech conditions matched in the plain text, even if the rule didn't
+}
+
+// Explain says how each rule did against the job, without changing its score
+func Explain(dbc *db.Job) []*RuleResult {
+	rs := GetRules()
+	rulesMutex.RLock() // for the profile
+	defer rulesMutex.RUnlock()
+	plain := plaintext.FromHTML(dbc.Text)
+	results := make([]*RuleResult, len(rs))
+	for i, r := range rs {
+		applies, known := matches(r, dbc, plain)
+		results[i] = &RuleResult{
+			Rule:    r,
+			Matched: applies && known,
+			Known:   known,
+			Spans:   spans(r, plain),
+		}
+	}
+	return results
+}
+
+// spans finds what the rule's text_found and tech conditions matched in plain
+func spans(rule *Rule, plain string) [][2]int {
+	var out [][2]int
+	for _, h := range rule.Highlightable() {
+		switch h.RuleType {
+		case TextFound:
+			for _, m := range h.Regex.FindAllStringIndex(plain, -1) {
+				out = append(out, [2]int{m[0], m[1]})
+			}
+		case TechIs:
+			for _, m := range techstack.FindAll(plain) {
+				if m.Key == h.Tech {
+					out = append(out, [2]int{m.Start, m.End})
+				}
+			}
+		}
+	}
+	slices.SortFunc(out, func(a, b [2]int) int { return a[0] - b[0] })
+	return out
+}
+
+// Matches reports whether the rule applies to the job
+func (r *Rule) Matches(dbc *db.Job) bool {
+	rulesMutex.RLock() // for the profile
+	defer rulesMutex.RUnlock()
+	applies, known := matches(r, dbc, plaintext.FromHTML(dbc.Text))
+	return applies && known
+}
+
+// Highlightable returns the text_found and tech conditions which help a rule match, i.e. the rule itself or the
+// conditions inside its "all" and "any" but not "not".  Conditions on a field or the raw HTML are left out, since what
+// they matched isn't in the plain text.
+func (r *Rule) Highlightable() []*Rule {
+	switch r.RuleType {
+	case TextFound:
+		if r.Field != "" || r.RawHTML {
+			return nil
+		}
+		return []*Rule{r}
+	case TechIs:
+		return []*Rule{r}
+	case All, Any:
+		var out []*Rule
+		for _, sub := range r.Sub {
+			out = append(out, sub.Highlightable()...)
+		}
+		return out
+	default:
+		return nil
+	}
+}
+
+// numericValue returns one of config.NumericFields for the job, or false if the job doesn't have it
+func numericValue(field string, dbc *db.Job) (float64, bool) {
+	min, max := dbc.Salary.Annualized()
+	if max == 0 {
+		max = min // e.g. "$200k+" or just "$150k"
+	}
+	var v int64
+	switch field {
+	case "salary_min":
+		v = min
+	case "salary_max":
+		v = max
+	default:
+		panic(fmt.Errorf("unhandled numeric field %q", field))
+	}
+	return float64(v), v != 0
+}
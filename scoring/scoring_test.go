@@ -0,0 +1,182 @@
+package scoring
+
+import (
+	"github.com/mwinters0/hnjobs/config"
+	"github.com/mwinters0/hnjobs/db"
+	"github.com/mwinters0/hnjobs/plaintext"
+	"github.com/mwinters0/hnjobs/salary"
+	"github.com/mwinters0/hnjobs/workmode"
+	"slices"
+	"testing"
+)
+
+// mustRule makes a Rule out of a config rule, skipping config validation
+func mustRule(t *testing.T, cr config.ScoringRule) *Rule {
+	t.Helper()
+	r, err := newRuleFromConf(&cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestHighlightable(t *testing.T) {
+	tests := []struct {
+		name string
+		rule config.ScoringRule
+		want int
+	}{
+		{"TextFound", config.ScoringRule{TextFound: "senior"}, 1},
+		{"Tech", config.ScoringRule{Tech: "go"}, 1},
+		{"Field", config.ScoringRule{TextFound: "senior", Field: "role"}, 0},
+		{"Scope", config.ScoringRule{TextFound: "senior", Field: "header"}, 0},
+		{"RawHTML", config.ScoringRule{TextFound: "<i>", RawHTML: true}, 0},
+		{"TextMissing", config.ScoringRule{TextMissing: "senior"}, 0},
+		{"All", config.ScoringRule{All: []config.ScoringRule{
+			{TextFound: "senior"},
+			{TextFound: "staff", Field: "role"},
+			{Not: &config.ScoringRule{TextFound: "junior"}},
+		}}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mustRule(t, tt.rule).Highlightable(); len(got) != tt.want {
+				t.Errorf("expected %d highlightable conditions, got %d", tt.want, len(got))
+			}
+		})
+	}
+}
+
+func TestMatchesUnknown(t *testing.T) {
+	// says nothing about the salary or work mode
+	vague := &db.Job{Text: "Acme | Backend<p>We use golang"}
+	specific := &db.Job{
+		Text:     "Acme | Backend | Remote | $150k<p>We use golang",
+		Salary:   salary.Salary{Min: 150000, Currency: "USD", Period: salary.PeriodAnnual},
+		WorkMode: workmode.WorkMode{Mode: workmode.ModeRemote},
+	}
+	yes := config.ScoringRule{TextFound: "golang"}
+	no := config.ScoringRule{TextFound: "python"}
+	noSalary := config.ScoringRule{Numeric: "salary_min >= 100k"}
+	compatible := true
+	noWorkMode := config.ScoringRule{RemoteCompatible: &compatible}
+	not := func(r config.ScoringRule) config.ScoringRule {
+		return config.ScoringRule{Not: &r}
+	}
+	allOf := func(rs ...config.ScoringRule) config.ScoringRule {
+		return config.ScoringRule{All: rs}
+	}
+	anyOf := func(rs ...config.ScoringRule) config.ScoringRule {
+		return config.ScoringRule{Any: rs}
+	}
+
+	tests := []struct {
+		name        string
+		rule        config.ScoringRule
+		job         *db.Job
+		wantApplies bool
+		wantKnown   bool
+	}{
+		{"salary missing", noSalary, vague, false, false},
+		{"salary listed", noSalary, specific, true, true},
+		{"work mode unknown", noWorkMode, vague, false, false},
+		{"work mode remote", noWorkMode, specific, true, true},
+		{"not salary missing", not(noSalary), vague, false, false},
+		{"not salary listed", not(noSalary), specific, false, true},
+		{"not work mode unknown", not(noWorkMode), vague, false, false},
+		{"not not salary missing", not(not(noSalary)), vague, false, false},
+		{"all true and unknown", allOf(yes, noSalary), vague, false, false},
+		{"all false and unknown", allOf(no, noWorkMode), vague, false, true},
+		{"all true and known", allOf(yes, noSalary, noWorkMode), specific, true, true},
+		{"any true and unknown", anyOf(noSalary, yes), vague, true, true},
+		{"any false and unknown", anyOf(no, noWorkMode), vague, false, false},
+		{"any false and false", anyOf(no, not(yes)), vague, false, true},
+		{"not all false and unknown", not(allOf(no, noSalary)), vague, true, true},
+		{"not all true and unknown", not(allOf(yes, noSalary)), vague, false, false},
+		{"not any true and unknown", not(anyOf(yes, noWorkMode)), vague, false, true},
+		{"all with not unknown", allOf(yes, not(noWorkMode)), vague, false, false},
+		{"any with not unknown", anyOf(not(noSalary), yes), vague, true, true},
+		{"all with not not false", allOf(not(not(no)), noSalary), vague, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			applies, known := matches(mustRule(t, tt.rule), tt.job, plaintext.FromHTML(tt.job.Text))
+			if applies != tt.wantApplies || known != tt.wantKnown {
+				t.Errorf(
+					"expected applies=%v known=%v, got applies=%v known=%v",
+					tt.wantApplies, tt.wantKnown, applies, known,
+				)
+			}
+		})
+	}
+}
+
+func TestScoreRuleHits(t *testing.T) {
+	confRules := []config.ScoringRule{
+		{TextFound: "(?i)sre", Score: 2},
+		{Tech: "kubernetes", Score: 3},
+		{All: []config.ScoringRule{{TextFound: "golang"}, {Not: &config.ScoringRule{TextFound: "python"}}}, Score: 1},
+		{TextFound: "python", Score: -5},
+		{Numeric: "salary_min >= 100k", Score: 4},
+		{TextFound: "SRE", Field: "role", Score: 10},
+	}
+	var rs []*Rule
+	for i, cr := range confRules {
+		r := mustRule(t, cr)
+		r.Index = i
+		rs = append(rs, r)
+	}
+	rulesMutex.Lock()
+	oldRules := rules
+	rules = rs
+	rulesMutex.Unlock()
+	t.Cleanup(func() {
+		rulesMutex.Lock()
+		rules = oldRules
+		rulesMutex.Unlock()
+	})
+
+	job := &db.Job{
+		Text: "Acme &amp; Co | SRE | Remote<p>SRE team, we use golang and k8s.<p>Sr&eacute; kubernetes too.",
+		Tech: []string{"go", "kubernetes"},
+	}
+	job.Header.Role = "SRE"
+	score := ScoreDBComment(job)
+	if score != 16 {
+		t.Errorf("expected score 16, got %d", score)
+	}
+	plain := plaintext.FromHTML(job.Text)
+	// what each rule's matches should be, by rule index
+	want := map[int][]string{
+		0: {"SRE", "SRE"},
+		1: {"k8s", "kubernetes"},
+		2: {"golang"},
+		5: nil, // matched the header field, which isn't highlighted
+	}
+	points := 0
+	for _, hit := range job.RuleHits {
+		points += hit.Points
+		wantMatches, ok := want[hit.RuleIndex]
+		if !ok {
+			t.Errorf("unexpected hit for rule %d", hit.RuleIndex)
+			continue
+		}
+		var got []string
+		for _, m := range hit.Matches {
+			if m[0] < 0 || m[0] > m[1] || m[1] > len(plain) {
+				t.Fatalf("rule %d: match %v is out of range for %q", hit.RuleIndex, m, plain)
+			}
+			got = append(got, plain[m[0]:m[1]])
+		}
+		if !slices.Equal(got, wantMatches) {
+			t.Errorf("rule %d: expected matches %q, got %q", hit.RuleIndex, wantMatches, got)
+		}
+		delete(want, hit.RuleIndex)
+	}
+	if points != score {
+		t.Errorf("hits add up to %d, but the score is %d", points, score)
+	}
+	if len(want) > 0 {
+		t.Errorf("missing hits for rules %v", want)
+	}
+}
@@ -0,0 +1,174 @@
+// Package techstack finds the technologies mentioned in a job posting using a built-in taxonomy, so that e.g. "golang"
+// and "Go" are both go, and "k8s" is kubernetes.
+package techstack
+
+import (
+	_ "embed"
+	"fmt"
+	"github.com/mwinters0/hnjobs/plaintext"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+//go:embed taxonomy.tsv
+var taxonomyTSV string
+
+type Tech struct {
+	Key      string // stable ID, e.g. "kubernetes"
+	Category string // language, database, cloud, ...
+	Name     string // for display, e.g. "Kubernetes"
+}
+
+type alias struct {
+	key       string
+	ambiguous bool
+}
+
+var techs map[string]*Tech
+var keys []string              // in taxonomy order
+var aliases map[string][]alias // matched text (lowercased if case-insensitive) -> alias
+var techRegex *regexp.Regexp
+var loadOnce sync.Once
+
+func load() {
+	techs = make(map[string]*Tech)
+	aliases = make(map[string][]alias)
+	var caseInsensitive, caseSensitive []string
+	addAlias := func(a string, key string) {
+		ambiguous := strings.HasPrefix(a, "~")
+		a = strings.TrimPrefix(a, "~")
+		if strings.ToLower(a) == a {
+			caseInsensitive = append(caseInsensitive, regexp.QuoteMeta(a))
+		} else {
+			caseSensitive = append(caseSensitive, regexp.QuoteMeta(a))
+		}
+		aliases[a] = append(aliases[a], alias{key, ambiguous})
+	}
+	for i, line := range strings.Split(taxonomyTSV, "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) < 3 {
+			panic(fmt.Errorf("taxonomy.tsv line %d: not enough fields", i+1))
+		}
+		t := &Tech{Key: fields[0], Category: fields[1], Name: fields[2]}
+		if _, dupe := techs[t.Key]; dupe {
+			panic(fmt.Errorf("taxonomy.tsv line %d: duplicate key %q", i+1, t.Key))
+		}
+		techs[t.Key] = t
+		keys = append(keys, t.Key)
+		var as []string
+		if len(fields) > 3 && fields[3] != "" {
+			as = strings.Split(fields[3], ",")
+		}
+		if !slices.Contains(as, "~"+t.Name) {
+			addAlias(strings.ToLower(t.Name), t.Key)
+		}
+		for _, a := range as {
+			addAlias(a, t.Key)
+		}
+	}
+	// Longest first, so that we find "React Native" rather than "React"
+	byLen := func(a, b string) int { return len(b) - len(a) }
+	slices.SortFunc(caseInsensitive, byLen)
+	slices.SortFunc(caseSensitive, byLen)
+	caseInsensitive = slices.Compact(caseInsensitive)
+	caseSensitive = slices.Compact(caseSensitive)
+	techRegex = regexp.MustCompile(
+		`(?:(?i:` + strings.Join(caseInsensitive, "|") + `)|(?:` + strings.Join(caseSensitive, "|") + `))`,
+	)
+}
+
+// Get returns the tech with the given key, or nil
+func Get(key string) *Tech {
+	loadOnce.Do(load)
+	return techs[key]
+}
+
+// Keys returns all known tech keys
+func Keys() []string {
+	loadOnce.Do(load)
+	return keys
+}
+
+// Match is a mention of a tech in some text
+type Match struct {
+	Key   string
+	Start int
+	End   int // exclusive
+}
+
+// FindAll returns every mention of a known tech in s, which is plain text
+func FindAll(s string) []Match {
+	loadOnce.Do(load)
+	var matches []Match
+	for _, loc := range techRegex.FindAllStringIndex(s, -1) {
+		if !isBoundary(s, loc[0], loc[1]) {
+			continue
+		}
+		text := s[loc[0]:loc[1]]
+		candidates, ok := aliases[text] // case-sensitive
+		if !ok {
+			candidates = aliases[strings.ToLower(text)]
+		}
+		for _, a := range candidates {
+			if a.ambiguous && !inList(s, loc[0], loc[1]) {
+				continue
+			}
+			matches = append(matches, Match{a.key, loc[0], loc[1]})
+			break
+		}
+	}
+	return matches
+}
+
+// Find returns the keys of the techs mentioned in s, in the order they're first mentioned.  s can be HTML.
+func Find(s string) []string {
+	plain := plaintext.FromHTML(s)
+	var found []string
+	for _, m := range FindAll(plain) {
+		if !slices.Contains(found, m.Key) {
+			found = append(found, m.Key)
+		}
+	}
+	return found
+}
+
+// isBoundary checks that s[start:end] is a whole word.  We can't use \b because of names like "C++" and ".NET".
+func isBoundary(s string, start, end int) bool {
+	isWordy := func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '+' || r == '#'
+	}
+	if start > 0 {
+		r, _ := utf8.DecodeLastRuneInString(s[:start])
+		if isWordy(r) || (r == '.' && start > 1 && isWordy(rune(s[start-2]))) {
+			return false // e.g. "foo.net"
+		}
+	}
+	if end < len(s) {
+		r, _ := utf8.DecodeRuneInString(s[end:])
+		if isWordy(r) {
+			return false
+		}
+		if r == '.' && end+1 < len(s) && isWordy(rune(s[end+1])) {
+			return false // e.g. "go.dev"
+		}
+	}
+	return true
+}
+
+var listBefore = regexp.MustCompile(`(?i)(?:[,/|(+&:]|\b(?:in|with|using|and|or|like|e\.g\.))\s*$`)
+var listAfter = regexp.MustCompile(`(?i)^\s*(?:[,/|)+&]|(?:and|or|developers?|engineers?|experience|services|backend|code)\b)`)
+
+// inList guesses whether s[start:end] is in a list of technologies, e.g. "Python, Go and Rust" but not "Go to our
+// website".
+func inList(s string, start, end int) bool {
+	before := s[max(0, start-12):start]
+	after := s[end:min(len(s), end+14)]
+	return listBefore.MatchString(before) || listAfter.MatchString(after)
+}
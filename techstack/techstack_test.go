@@ -0,0 +1,60 @@
+package techstack
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTaxonomy(t *testing.T) {
+	loadOnce.Do(load) // panics on bad data
+	if len(techs) < 100 {
+		t.Errorf("expected lots of techs, got %d", len(techs))
+	}
+	for _, k := range Keys() {
+		if Get(k) == nil {
+			t.Errorf("missing %q", k)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	tests := map[string][]string{
+		"We use golang, k8s and PostgreSQL":               {"go", "kubernetes", "postgres"},
+		"Python, Go and Rust":                             {"python", "go", "rust"},
+		"Written in Go.":                                  {"go"},
+		"Go to our website. Let's go!":                    nil,
+		"C/C++ and C# developers":                         {"c", "cpp", "csharp"},
+		"We're a .NET shop on Azure":                      {"dotnet", "azure"},
+		"React Native and React":                          {"react-native", "react"},
+		"We react quickly":                                nil,
+		"Stats in R, Python":                              {"r", "python"},
+		"Visit example.net or go.dev":                     nil,
+		"TypeScript/Node.js, deployed with Terraform":     {"typescript", "nodejs", "terraform"},
+		"Our algorithm uses mongodb and django templates": {"mongodb", "django"},
+	}
+	for s, want := range tests {
+		t.Run(s, func(t *testing.T) {
+			got := Find(s)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestFindAll(t *testing.T) {
+	s := "Go and golang"
+	got := FindAll(s)
+	want := []Match{{"go", 0, 2}, {"go", 7, 13}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestFindHTML(t *testing.T) {
+	got := Find("Acme | Backend | Remote<p>We use Python<p>Go &amp; Postgres")
+	want := []string{"python", "go", "postgres"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
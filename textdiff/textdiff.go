@@ -0,0 +1,139 @@
+// Package textdiff does word-level diffs, for showing what changed when a job posting is edited
+package textdiff
+
+import (
+	"strings"
+	"unicode"
+)
+
+type OpKind int
+
+const (
+	Equal OpKind = iota
+	Insert
+	Delete
+)
+
+// Op is a run of text which is unchanged, added or removed
+type Op struct {
+	Kind OpKind
+	Text string
+}
+
+// Words diffs a and b word by word.  Joining the Equal and Delete ops gives a, and joining the Equal and Insert ops
+// gives b.  Whitespace changes only show up if the words around them changed.
+func Words(a, b string) []Op {
+	at := tokenize(a)
+	bt := tokenize(b)
+	// trim the common prefix and suffix first, since edits are usually small
+	prefix := 0
+	for prefix < len(at) && prefix < len(bt) && at[prefix] == bt[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(at)-prefix && suffix < len(bt)-prefix && at[len(at)-1-suffix] == bt[len(bt)-1-suffix] {
+		suffix++
+	}
+	var ops []Op
+	var deleted, inserted strings.Builder
+	add := func(kind OpKind, text string) {
+		if text == "" {
+			return
+		}
+		if len(ops) > 0 && ops[len(ops)-1].Kind == kind {
+			ops[len(ops)-1].Text += text
+			return
+		}
+		ops = append(ops, Op{kind, text})
+	}
+	flush := func() {
+		// deletions first, then insertions, so that a changed phrase reads as "-old- +new+"
+		add(Delete, deleted.String())
+		add(Insert, inserted.String())
+		deleted.Reset()
+		inserted.Reset()
+	}
+	add(Equal, strings.Join(at[:prefix], ""))
+	changes := lcsDiff(at[prefix:len(at)-suffix], bt[prefix:len(bt)-suffix])
+	for i, op := range changes {
+		switch op.Kind {
+		case Delete:
+			deleted.WriteString(op.Text)
+		case Insert:
+			inserted.WriteString(op.Text)
+		case Equal:
+			pending := deleted.Len() > 0 || inserted.Len() > 0
+			if pending && strings.TrimSpace(op.Text) == "" && i+1 < len(changes) && changes[i+1].Kind != Equal {
+				// a lone space between changes is noise, e.g. "-foo- -bar-" should be "-foo bar-"
+				deleted.WriteString(op.Text)
+				inserted.WriteString(op.Text)
+				continue
+			}
+			flush()
+			add(Equal, op.Text)
+		}
+	}
+	flush()
+	add(Equal, strings.Join(at[len(at)-suffix:], ""))
+	return ops
+}
+
+// tokenize splits s into alternating runs of whitespace and non-whitespace
+func tokenize(s string) []string {
+	var tokens []string
+	start := 0
+	prevSpace := false
+	for i, r := range s {
+		space := unicode.IsSpace(r)
+		if i > 0 && space != prevSpace {
+			tokens = append(tokens, s[start:i])
+			start = i
+		}
+		prevSpace = space
+	}
+	if start < len(s) {
+		tokens = append(tokens, s[start:])
+	}
+	return tokens
+}
+
+// lcsDiff is the textbook longest-common-subsequence diff.  It's O(len(a)*len(b)) but job postings are short.
+func lcsDiff(a, b []string) []Op {
+	// lcs[i][j] is the LCS length of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var ops []Op
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, Op{Equal, a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, Op{Delete, a[i]})
+			i++
+		default:
+			ops = append(ops, Op{Insert, b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, Op{Delete, a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, Op{Insert, b[j]})
+	}
+	return ops
+}
@@ -0,0 +1,47 @@
+package textdiff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWords(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []Op
+	}{
+		{"Same", "a b c", "a b c", []Op{{Equal, "a b c"}}},
+		{"Empty", "", "", nil},
+		{"Insert", "Salary: $150k", "Salary: $150k-$180k DOE", []Op{
+			{Equal, "Salary: "}, {Delete, "$150k"}, {Insert, "$150k-$180k DOE"},
+		}},
+		{"Delete", "Hiring SRE and frontend", "Hiring SRE", []Op{{Equal, "Hiring SRE"}, {Delete, " and frontend"}}},
+		{"Phrase", "we use old slow stuff here", "we use new fast stuff here", []Op{
+			{Equal, "we use "}, {Delete, "old slow"}, {Insert, "new fast"}, {Equal, " stuff here"},
+		}},
+		{"FromNothing", "", "new text", []Op{{Insert, "new text"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Words(tt.a, tt.b)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			// we can always get the originals back
+			var a, b strings.Builder
+			for _, op := range got {
+				if op.Kind != Insert {
+					a.WriteString(op.Text)
+				}
+				if op.Kind != Delete {
+					b.WriteString(op.Text)
+				}
+			}
+			if a.String() != tt.a || b.String() != tt.b {
+				t.Errorf("ops don't reconstruct the inputs: %q / %q", a.String(), b.String())
+			}
+		})
+	}
+}
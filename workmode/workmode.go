@@ -0,0 +1,297 @@
+// Package workmode classifies a job as remote / hybrid / onsite, and finds any restrictions on where remote workers
+// can be, e.g. "Remote (US only)" or "UTC-3 to UTC+3".
+package workmode
+
+import (
+	"github.com/mwinters0/hnjobs/plaintext"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type Mode string
+
+const (
+	ModeUnknown Mode = ""
+	ModeRemote  Mode = "remote"
+	ModeHybrid  Mode = "hybrid"
+	ModeOnsite  Mode = "onsite"
+)
+
+// ParseMode is the inverse of String()
+func ParseMode(s string) (Mode, bool) {
+	switch s {
+	case "unknown":
+		return ModeUnknown, true
+	case "remote", "hybrid", "onsite":
+		return Mode(s), true
+	}
+	return ModeUnknown, false
+}
+
+func (m Mode) String() string {
+	if m == ModeUnknown {
+		return "unknown"
+	}
+	return string(m)
+}
+
+// TZWindow is the range of UTC offsets (in hours) where remote workers must be
+type TZWindow struct {
+	Min float64
+	Max float64
+}
+
+type WorkMode struct {
+	Mode    Mode
+	Regions []string  `json:",omitempty"` // where remote workers must be, see RegionContains
+	TZ      *TZWindow `json:",omitempty"`
+}
+
+var (
+	sentenceEnd  = regexp.MustCompile(`[.!?;]+\s|\n`)
+	notRemote    = regexp.MustCompile(`(?i)\b(not|no|non)[- ]remote\b|\bremote[- ](is )?not\b`)
+	remoteRegex  = regexp.MustCompile(`(?i)\b(remote|wfh|work from home|distributed team|fully distributed)\b`)
+	onsiteRegex  = regexp.MustCompile(`(?i)\b(on-?site|on site|in-office|in office|in-person|in person)\b`)
+	hybridRegex  = regexp.MustCompile(`(?i)\bhybrid\b|\b\d days? (a|per) week in\b|\bdays? in (the )?office\b`)
+	contextRegex = regexp.MustCompile(`(?i)remote|time ?zones?|\bUTC|\bGMT|hybrid`)
+)
+
+// Classify looks at the remote segment of the job's header (see jobheader) first, and then the rest of text, which is
+// HTML.
+func Classify(headerRemote string, text string) WorkMode {
+	wm := WorkMode{}
+	wm.Mode = classifyMode(headerRemote)
+	plain := plaintext.FromHTML(text)
+	if wm.Mode == ModeUnknown {
+		wm.Mode = classifyMode(plain)
+	}
+	if wm.Mode != ModeRemote && wm.Mode != ModeHybrid {
+		return wm
+	}
+
+	// Only look for restrictions where people talk about remote work, so that e.g. "our US customers" doesn't count.
+	var context []string
+	if headerRemote != "" {
+		context = append(context, headerRemote)
+	}
+	for _, s := range sentenceEnd.Split(plain, -1) {
+		if contextRegex.MatchString(s) {
+			context = append(context, s)
+		}
+	}
+	for _, c := range context {
+		for _, r := range findRegions(c) {
+			if !slices.Contains(wm.Regions, r) {
+				wm.Regions = append(wm.Regions, r)
+			}
+		}
+		if wm.TZ == nil {
+			wm.TZ = findTZWindow(c)
+		}
+	}
+	return wm
+}
+
+func classifyMode(s string) Mode {
+	if s == "" {
+		return ModeUnknown
+	}
+	if hybridRegex.MatchString(s) {
+		return ModeHybrid
+	}
+	onsite := onsiteRegex.MatchString(s)
+	remote := false
+	if notRemote.MatchString(s) {
+		onsite = true
+	} else {
+		remote = remoteRegex.MatchString(s)
+	}
+	switch {
+	case remote && onsite:
+		// e.g. "REMOTE-friendly, onsite preferred" or "SF or Remote"
+		return ModeHybrid
+	case remote:
+		return ModeRemote
+	case onsite:
+		return ModeOnsite
+	}
+	return ModeUnknown
+}
+
+// === regions
+
+// region is a place which remote jobs can be restricted to.  Countries use their ISO 3166 alpha-2 code.
+type region struct {
+	code      string
+	pattern   *regexp.Regexp
+	countries []string // nil for a single country
+}
+
+var eu = []string{
+	"AT", "BE", "BG", "HR", "CY", "CZ", "DK", "EE", "FI", "FR", "DE", "GR", "HU", "IE", "IT", "LV", "LT", "LU", "MT",
+	"NL", "PL", "PT", "RO", "SK", "SI", "ES", "SE",
+}
+var europe = slices.Concat(eu, []string{"GB", "CH", "NO", "IS", "UA", "RS", "BA", "ME", "MK", "AL", "MD", "LI"})
+var northAmerica = []string{"US", "CA", "MX"}
+var latam = []string{"MX", "BR", "AR", "CO", "CL", "PE", "UY", "PY", "BO", "EC", "VE", "CR", "PA", "GT", "DO", "SV", "HN", "NI"}
+var apac = []string{"AU", "NZ", "JP", "KR", "SG", "IN", "PH", "ID", "VN", "MY", "TH", "TW", "HK", "CN", "PK", "BD", "LK"}
+var emea = slices.Concat(europe, []string{"IL", "AE", "SA", "QA", "TR", "EG", "ZA", "NG", "KE", "MA", "GH"})
+
+// Order matters: longer names first, so that "North America" isn't found as "America".
+var regions = []region{
+	{"WORLDWIDE", regexp.MustCompile(`(?i)\b(worldwide|anywhere|global(ly)?|any (time ?zone|country|location))\b`), []string{}},
+	{"EMEA", regexp.MustCompile(`\bEMEA\b`), emea},
+	{"APAC", regexp.MustCompile(`\bAPAC\b|(?i)\basia[- ]pacific\b`), apac},
+	{"LATAM", regexp.MustCompile(`\bLATAM\b|(?i)\blatin america\b|\bsouth america\b`), latam},
+	{"NA", regexp.MustCompile(`(?i)\bnorth america\b`), northAmerica},
+	{"AMERICAS", regexp.MustCompile(`(?i)\bthe americas\b|\bAMER\b`), slices.Concat(northAmerica, latam)},
+	{"EU", regexp.MustCompile(`\bEU\b|(?i)\beuropean union\b`), eu},
+	{"EUROPE", regexp.MustCompile(`(?i)\beurope\b`), europe},
+	{"US", regexp.MustCompile(`(?:\bUSA?\b|\bU\.S\.(?:A\.)?)|(?i)\bunited states\b|\bamerica\b`), nil},
+	{"CA", regexp.MustCompile(`(?i)\bcanada\b`), nil},
+	{"GB", regexp.MustCompile(`\bUK\b|(?i)\bunited kingdom\b|\bbritain\b|\bengland\b`), nil},
+	{"DE", regexp.MustCompile(`(?i)\bgermany\b`), nil},
+	{"FR", regexp.MustCompile(`(?i)\bfrance\b`), nil},
+	{"NL", regexp.MustCompile(`(?i)\bnetherlands\b`), nil},
+	{"ES", regexp.MustCompile(`(?i)\bspain\b`), nil},
+	{"PT", regexp.MustCompile(`(?i)\bportugal\b`), nil},
+	{"IE", regexp.MustCompile(`(?i)\bireland\b`), nil},
+	{"PL", regexp.MustCompile(`(?i)\bpoland\b`), nil},
+	{"SE", regexp.MustCompile(`(?i)\bsweden\b`), nil},
+	{"CH", regexp.MustCompile(`(?i)\bswitzerland\b`), nil},
+	{"IN", regexp.MustCompile(`(?i)\bindia\b`), nil},
+	{"AU", regexp.MustCompile(`(?i)\baustralia\b`), nil},
+	{"NZ", regexp.MustCompile(`(?i)\bnew zealand\b`), nil},
+	{"BR", regexp.MustCompile(`(?i)\bbrazil\b`), nil},
+	{"MX", regexp.MustCompile(`(?i)\bmexico\b`), nil},
+	{"JP", regexp.MustCompile(`(?i)\bjapan\b`), nil},
+	{"SG", regexp.MustCompile(`(?i)\bsingapore\b`), nil},
+	{"IL", regexp.MustCompile(`(?i)\bisrael\b`), nil},
+}
+
+func findRegions(s string) []string {
+	var found []string
+	for _, r := range regions {
+		if loc := r.pattern.FindStringIndex(s); loc != nil {
+			found = append(found, r.code)
+			s = s[:loc[0]] + s[loc[1]:] // so e.g. "North America" doesn't also count as "America"
+		}
+	}
+	return found
+}
+
+// RegionContains reports whether the region code (as found in WorkMode.Regions) includes the country code
+func RegionContains(regionCode string, country string) bool {
+	country = strings.ToUpper(country)
+	if country == "UK" {
+		country = "GB"
+	}
+	for _, r := range regions {
+		if r.code != regionCode {
+			continue
+		}
+		if r.code == "WORLDWIDE" {
+			return true
+		}
+		if r.countries == nil {
+			return r.code == country
+		}
+		return slices.Contains(r.countries, country)
+	}
+	return false
+}
+
+// === timezones
+
+var tzAbbreviations = map[string]float64{
+	"UTC": 0, "GMT": 0, "WET": 0, "BST": 1, "CET": 1, "CEST": 2, "EET": 2, "EEST": 3, "IST": 5.5, "SGT": 8, "JST": 9,
+	"AEST": 10, "AEDT": 11, "NZST": 12, "ET": -5, "EST": -5, "EDT": -4, "CT": -6, "CST": -6, "CDT": -5, "MT": -7,
+	"MST": -7, "MDT": -6, "PT": -8, "PST": -8, "PDT": -7, "BRT": -3, "ART": -3,
+}
+
+const tzNames = `(?:UTC|GMT)\s?[+\-−]\s?\d{1,2}(?::\d{2})?|UTC|GMT|[ECMP][SD]T|CES?T|EES?T|WET|BST|IST|SGT|JST|AE[SD]T|NZST|BRT|ART`
+const tzTerm = `\b(` + tzNames + `)\b`
+
+// tzTermNearHours also has the bare "ET", "PT" etc., which are too ambiguous ("FT/PT") without a number of hours
+const tzTermNearHours = `\b(` + tzNames + `|[ECMP]T)\b`
+
+var (
+	tzRangeRegex     = regexp.MustCompile(tzTerm + `\s*(?:to|-|–|—|through|and)\s*` + tzTerm)
+	tzPlusMinusRegex = regexp.MustCompile(`(?i)(?:within|±|\+/-|\+-)\s*(\d{1,2})\s*(?:hours?|hrs?|h)?\s*(?:of\s+)?` + `(?-i)` + tzTermNearHours)
+	tzTermPlusMinus  = regexp.MustCompile(tzTermNearHours + `\s*(?:±|\+/-|\+-)\s*(\d{1,2})`)
+	tzOffsetRegex    = regexp.MustCompile(`^(?:UTC|GMT)\s?([+\-−])\s?(\d{1,2})(?::(\d{2}))?$`)
+)
+
+// ParseOffset parses "UTC+5:30", "GMT-3" or a known abbreviation like "CET" into hours from UTC.
+func ParseOffset(s string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	if v, ok := tzAbbreviations[strings.ToUpper(s)]; ok {
+		return v, true
+	}
+	m := tzOffsetRegex.FindStringSubmatch(strings.ToUpper(s))
+	if m == nil {
+		return 0, false
+	}
+	h, _ := strconv.Atoi(m[2])
+	v := float64(h)
+	if m[3] != "" {
+		mins, _ := strconv.Atoi(m[3])
+		v += float64(mins) / 60
+	}
+	if m[1] != "+" {
+		v = -v
+	}
+	return v, true
+}
+
+func findTZWindow(s string) *TZWindow {
+	if m := tzRangeRegex.FindStringSubmatch(s); m != nil {
+		a, okA := ParseOffset(m[1])
+		b, okB := ParseOffset(m[2])
+		if okA && okB {
+			return &TZWindow{min(a, b), max(a, b)}
+		}
+	}
+	var center, spread string
+	if m := tzPlusMinusRegex.FindStringSubmatch(s); m != nil {
+		spread, center = m[1], m[2]
+	} else if m := tzTermPlusMinus.FindStringSubmatch(s); m != nil {
+		center, spread = m[1], m[2]
+	}
+	if center != "" {
+		c, ok := ParseOffset(center)
+		h, err := strconv.Atoi(spread)
+		if ok && err == nil {
+			return &TZWindow{c - float64(h), c + float64(h)}
+		}
+	}
+	return nil
+}
+
+// === compatibility
+
+// Allows reports whether someone in country (ISO 3166 alpha-2) at UTC offset tz (in hours) can take this remote job.
+// Empty country or nil tz mean unknown, which doesn't rule anything out.  Likewise a job which doesn't mention any
+// restrictions allows everyone.
+func (wm *WorkMode) Allows(country string, tz *float64) bool {
+	if country != "" && len(wm.Regions) > 0 {
+		ok := false
+		for _, r := range wm.Regions {
+			if RegionContains(r, country) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	if tz != nil && wm.TZ != nil {
+		if *tz < wm.TZ.Min || *tz > wm.TZ.Max {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,118 @@
+package workmode
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name         string
+		headerRemote string
+		text         string
+		want         WorkMode
+	}{
+		{
+			name: "NotRemote",
+			text: "Acme | SRE | NYC<p>This role is not remote.",
+			want: WorkMode{Mode: ModeOnsite},
+		},
+		{
+			name:         "RemoteUSOnly",
+			headerRemote: "remote (US only)",
+			text:         "Acme | SRE | remote (US only)<p>We sell to EU customers.",
+			want:         WorkMode{Mode: ModeRemote, Regions: []string{"US"}},
+		},
+		{
+			name:         "RemoteUSOnlyDotted",
+			headerRemote: "Remote (U.S. only)",
+			text:         "Acme | SRE | Remote (U.S. only)",
+			want:         WorkMode{Mode: ModeRemote, Regions: []string{"US"}},
+		},
+		{
+			name: "RemoteUSADotted",
+			text: "Acme | SRE<p>Remote, U.S.A. residents only.",
+			want: WorkMode{Mode: ModeRemote, Regions: []string{"US"}},
+		},
+		{
+			name:         "RemoteFriendly",
+			headerRemote: "REMOTE-friendly, onsite preferred",
+			text:         "Acme | SRE | REMOTE-friendly, onsite preferred",
+			want:         WorkMode{Mode: ModeHybrid},
+		},
+		{
+			name: "TZRange",
+			text: "Acme | SRE<p>Fully remote, but you must be between UTC-3 to UTC+3.",
+			want: WorkMode{Mode: ModeRemote, TZ: &TZWindow{-3, 3}},
+		},
+		{
+			name: "TZPlusMinus",
+			text: "Acme | SRE | Remote (North America)<p>Remote within 3 hours of EST.",
+			want: WorkMode{Mode: ModeRemote, Regions: []string{"NA"}, TZ: &TZWindow{-8, -2}},
+		},
+		{
+			name: "TZPlusMinusBare",
+			text: "Acme | SRE | Remote<p>Remote within 2 hours of PT.",
+			want: WorkMode{Mode: ModeRemote, TZ: &TZWindow{-10, -6}},
+		},
+		{
+			name: "NotTZ",
+			text: "Acme | SRE | Remote | FT/PT - CT team",
+			want: WorkMode{Mode: ModeRemote},
+		},
+		{
+			name:         "Hybrid",
+			headerRemote: "Hybrid (3 days in office)",
+			text:         "Acme | SRE | Berlin | Hybrid (3 days in office)",
+			want:         WorkMode{Mode: ModeHybrid},
+		},
+		{
+			name: "Unknown",
+			text: "Acme | SRE | Berlin<p>We make anvils.",
+			want: WorkMode{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Classify(tt.headerRemote, tt.text)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got  %+v (tz %+v)\nwant %+v (tz %+v)", got, got.TZ, tt.want, tt.want.TZ)
+			}
+		})
+	}
+}
+
+func TestParseOffset(t *testing.T) {
+	tests := map[string]float64{"UTC+5:30": 5.5, "GMT-3": -3, "UTC": 0, "cet": 1, "PST": -8}
+	for s, want := range tests {
+		got, ok := ParseOffset(s)
+		if !ok || got != want {
+			t.Errorf("%s: got %v (%v), want %v", s, got, ok, want)
+		}
+	}
+	if _, ok := ParseOffset("Mars/Olympus_Mons"); ok {
+		t.Error("expected failure")
+	}
+}
+
+func TestAllows(t *testing.T) {
+	berlin := 1.0
+	wm := WorkMode{Mode: ModeRemote, Regions: []string{"EU"}, TZ: &TZWindow{-3, 3}}
+	if !wm.Allows("DE", &berlin) {
+		t.Error("expected DE to be allowed")
+	}
+	if wm.Allows("US", &berlin) {
+		t.Error("expected US to not be allowed")
+	}
+	nyc := -5.0
+	if wm.Allows("", &nyc) {
+		t.Error("expected UTC-5 to not be allowed")
+	}
+	if !wm.Allows("", nil) {
+		t.Error("expected unknown to be allowed")
+	}
+	anywhere := WorkMode{Mode: ModeRemote, Regions: []string{"WORLDWIDE"}}
+	if !anywhere.Allows("NZ", nil) {
+		t.Error("expected worldwide to allow everyone")
+	}
+}